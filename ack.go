@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+
+	"github.com/gorilla/websocket"
+)
+
+// parseSeq extracts the optional "seq" of a client message. Only whole
+// numbers are accepted.
+func parseSeq(value interface{}) (int64, bool) {
+	seq, ok := value.(float64)
+	if !ok || seq != math.Trunc(seq) {
+		return 0, false
+	}
+	return int64(seq), true
+}
+
+// sendAck tells the sender whether its message reached every peer. It runs
+// in room.run() after the message was broadcast.
+func (r *Room) sendAck(sender *websocket.Conn, seq int64, delivered bool) {
+	payload := map[string]interface{}{
+		"type": "ack",
+		"seq":  seq,
+	}
+	if !delivered {
+		r.sendNack(sender, seq, "peerError")
+		return
+	}
+	if err := r.writeJSON(sender, payload); err != nil {
+		slog.Error("Error sending ack", slog.String("room", r.id), remoteAttr(sender), slog.Int64("seq", seq), slog.Any("error", err))
+	}
+}
+
+// sendNack tells the sender why its message was not delivered.
+func (r *Room) sendNack(sender *websocket.Conn, seq int64, reason string) {
+	if err := r.writeJSON(sender, map[string]interface{}{
+		"type":   "nack",
+		"seq":    seq,
+		"reason": reason,
+	}); err != nil {
+		slog.Error("Error sending nack", slog.String("room", r.id), remoteAttr(sender), slog.Int64("seq", seq), slog.Any("error", err))
+	}
+}
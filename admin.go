@@ -0,0 +1,180 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// requireAdmin only lets requests through that carry Config.AdminToken as a
+// bearer token. Admin endpoints are disabled while no token is configured.
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.config.AdminToken == "" {
+			http.Error(w, "admin API is disabled", http.StatusForbidden)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AdminToken)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// dedupeCategories trims names, normalizes tags and removes empty and
+// duplicate entries while keeping the original order.
+func dedupeCategories(categories []CategoryEntry) []CategoryEntry {
+	seen := make(map[string]bool, len(categories))
+	result := make([]CategoryEntry, 0, len(categories))
+	for _, category := range categories {
+		name := strings.TrimSpace(category.Name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, CategoryEntry{Name: name, Tags: normalizeTags(category.Tags)})
+	}
+	return result
+}
+
+// categoryPackParam returns the pack selected with ?pack=, defaulting to
+// the default pack.
+func categoryPackParam(r *http.Request) string {
+	if pack := r.URL.Query().Get("pack"); pack != "" {
+		return pack
+	}
+	return defaultPack
+}
+
+func (s *Server) handleGetCategories(w http.ResponseWriter, r *http.Request) {
+	pack := categoryPackParam(r)
+	categories, ok := s.getCategoryPack(pack)
+	if !ok {
+		http.Error(w, "unknown category pack", http.StatusNotFound)
+		return
+	}
+
+	used := 0
+	for _, info := range s.roomInfos() {
+		if info.Pack == pack {
+			used += info.UsedCategories
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"pack":           pack,
+		"categories":     categories,
+		"count":          len(categories),
+		"usedCategories": used,
+	})
+}
+
+func (s *Server) handlePutCategories(w http.ResponseWriter, r *http.Request) {
+	var categories Categories
+	if err := json.NewDecoder(r.Body).Decode(&categories); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	list := dedupeCategories(categories.Categories)
+	if len(list) == 0 {
+		http.Error(w, "categories must not be empty", http.StatusBadRequest)
+		return
+	}
+
+	pack := categoryPackParam(r)
+	s.setCategoryPack(pack, list)
+	slog.Info("Replaced categories", slog.String("pack", pack), slog.Int("count", len(list)))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"pack":  pack,
+		"count": len(list),
+	})
+}
+
+func (s *Server) handleCloseRoom(w http.ResponseWriter, r *http.Request) {
+	if err := s.CloseRoom(r.PathValue("roomID")); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// kickRequestBody is the body accepted by POST /admin/rooms/{roomID}/kick.
+type kickRequestBody struct {
+	ClientID string `json:"clientId"`
+}
+
+func (s *Server) handleKickClient(w http.ResponseWriter, r *http.Request) {
+	var req kickRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ClientID == "" {
+		http.Error(w, "clientId is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.KickClient(r.PathValue("roomID"), req.ClientID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleGetSnapshot(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := s.Snapshot(r.PathValue("roomID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+func (s *Server) handleRestoreSnapshot(w http.ResponseWriter, r *http.Request) {
+	var snapshot RoomSnapshot
+	if err := json.NewDecoder(r.Body).Decode(&snapshot); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if snapshot.Revealed < 0 || snapshot.Round < 0 {
+		http.Error(w, "revealed and round must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	restored, err := s.RestoreSnapshot(r.PathValue("roomID"), snapshot)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(restored)
+}
+
+func (s *Server) handleGetEvents(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if value := r.URL.Query().Get("since"); value != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, value); err != nil {
+			http.Error(w, "since must be an RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+	}
+
+	room, ok := s.GetRoom(r.PathValue("roomID"))
+	if !ok {
+		http.Error(w, ErrRoomNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(room.eventsSince(since))
+}
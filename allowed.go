@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+)
+
+// requestAllowedTypes queues a host's "setAllowedTypes" for room.run(). A
+// missing or null "types" allows every message type again. It runs in the
+// connection's reader goroutine.
+func (r *Room) requestAllowedTypes(conn *websocket.Conn, msg map[string]interface{}) {
+	var types []MessageType
+	switch value := msg["types"].(type) {
+	case nil:
+	case []interface{}:
+		types = make([]MessageType, 0, len(value))
+		for _, item := range value {
+			name, ok := item.(string)
+			if !ok {
+				r.rejectMessage(conn, &InvalidMessageError{Field: "types", Reason: "invalidType"})
+				return
+			}
+			types = append(types, MessageType(name))
+		}
+	default:
+		r.rejectMessage(conn, &InvalidMessageError{Field: "types", Reason: "invalidType"})
+		return
+	}
+
+	message, err := json.Marshal(types)
+	if err != nil {
+		slog.Error("Error encoding allowed types", slog.String("room", r.id), slog.Any("error", err))
+		return
+	}
+	enqueue(r, r.broadcast, BroadcastMessage{
+		message: message,
+		sender:  conn,
+		msgType: TypeSetAllowedTypes,
+		control: true,
+	})
+}
+
+// handleSetAllowedTypes applies a queued "setAllowedTypes" and tells the
+// room which types are allowed now. It runs in room.run().
+func (r *Room) handleSetAllowedTypes(broadcastMsg BroadcastMessage) {
+	var types []MessageType
+	if err := json.Unmarshal(broadcastMsg.message, &types); err != nil {
+		slog.Error("Error decoding allowed types", slog.String("room", r.id), slog.Any("error", err))
+		return
+	}
+	r.setAllowedTypes(types)
+
+	r.logEvent("allowedTypesChanged", map[string]interface{}{
+		"clientId": r.clientIDs[broadcastMsg.sender],
+		"types":    types,
+	})
+	announcement, err := newBroadcast(nil, map[string]interface{}{
+		"type":  "allowedTypesChanged",
+		"types": types,
+	})
+	if err != nil {
+		slog.Error("Error marshalling allowedTypesChanged message", slog.String("room", r.id), slog.Any("error", err))
+		return
+	}
+	r.broadcastMessage(announcement)
+}
+
+// setAllowedTypes restricts the messages players may broadcast to types.
+// nil allows every type. Game modes can call it from room.run(), e.g. from
+// a hook, to lock communication to the current phase of the game.
+func (r *Room) setAllowedTypes(types []MessageType) {
+	if types == nil {
+		r.allowedTypes = nil
+		return
+	}
+	r.allowedTypes = make(map[MessageType]bool, len(types))
+	for _, msgType := range types {
+		r.allowedTypes[msgType] = true
+	}
+}
+
+// allowed reports whether a message queued for room.run() may go ahead.
+// Only player messages are restricted; server messages, messages from other
+// instances and "setAllowedTypes" itself always pass. Players get a
+// "messageNotAllowed" for messages that are held back. It runs in
+// room.run().
+func (r *Room) allowed(broadcastMsg BroadcastMessage) bool {
+	if r.allowedTypes == nil || r.allowedTypes[broadcastMsg.msgType] {
+		return true
+	}
+	if broadcastMsg.msgType == TypeSetAllowedTypes || !ValidMessageTypes[broadcastMsg.msgType] || !r.clients[broadcastMsg.sender] {
+		return true
+	}
+
+	if err := r.writeJSON(broadcastMsg.sender, map[string]interface{}{
+		"type":    "messageNotAllowed",
+		"msgType": broadcastMsg.msgType,
+	}); err != nil {
+		r.connLogger(broadcastMsg.sender).Error("Error sending messageNotAllowed message", slog.String("room", r.id), remoteAttr(broadcastMsg.sender), slog.Any("error", err))
+	}
+	return false
+}
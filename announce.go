@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// announcementInterval is the minimum time between two server-wide
+// announcements.
+const announcementInterval = time.Minute
+
+// BroadcastToAllRooms queues msg for delivery to every player and spectator
+// in every active room, e.g. a maintenance notice. It goes through the
+// rooms' priority channel so it does not wait behind queued game messages.
+func (s *Server) BroadcastToAllRooms(msg []byte) {
+	var rooms []*Room
+	s.rooms.Range(func(_, value any) bool {
+		rooms = append(rooms, value.(*Room))
+		return true
+	})
+
+	for _, room := range rooms {
+		// A closed room would never take the message
+		select {
+		case room.priority <- BroadcastMessage{message: msg, msgType: "serverAnnouncement", includeSender: true}:
+		case <-room.ctx.Done():
+		}
+	}
+	slog.Info("Broadcast announcement to all rooms", slog.Int("rooms", len(rooms)))
+}
+
+// announcementBody is the body accepted by POST /admin/broadcast.
+type announcementBody struct {
+	Message string `json:"message"`
+}
+
+func (s *Server) handleAnnouncement(w http.ResponseWriter, r *http.Request) {
+	var body announcementBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Message == "" {
+		http.Error(w, "message is required", http.StatusBadRequest)
+		return
+	}
+
+	s.announceMu.Lock()
+	if wait := announcementInterval - time.Since(s.announcedAt); wait > 0 {
+		s.announceMu.Unlock()
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "only one announcement per minute", http.StatusTooManyRequests)
+		return
+	}
+	s.announcedAt = time.Now()
+	s.announceMu.Unlock()
+
+	msg, err := json.Marshal(map[string]interface{}{
+		"type":    "serverAnnouncement",
+		"message": body.Message,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.BroadcastToAllRooms(msg)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// BinaryGameMode is implemented by game modes that understand binary frames,
+// e.g. compact MessagePack or fixed-layout state updates for high frequency
+// data like drawing coordinates.
+type BinaryGameMode interface {
+	// HandleBinaryMessage processes a binary frame sent by conn. Returning
+	// ErrUnhandledMessage relays the frame to the peers unchanged. It is
+	// called from the connection's reader goroutine.
+	HandleBinaryMessage(room *Room, conn *websocket.Conn, payload []byte) error
+}
+
+// handleBinaryMessage passes a binary frame to the game mode, or relays it
+// as a binary frame if the mode does not handle it.
+func (r *Room) handleBinaryMessage(conn *websocket.Conn, payload []byte) {
+	if mode, ok := r.mode.(BinaryGameMode); ok {
+		err := mode.HandleBinaryMessage(r, conn, payload)
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, ErrUnhandledMessage) {
+			slog.Error("Error handling binary message", slog.String("room", r.id), remoteAttr(conn), slog.Any("error", err))
+			return
+		}
+	}
+	enqueue(r, r.broadcast, BroadcastMessage{message: payload, sender: conn, includeSender: false, frameType: websocket.BinaryMessage, timestamp: time.Now()})
+}
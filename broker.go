@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Broker relays room broadcasts between server instances so clients of the
+// same room can be connected to different instances.
+type Broker interface {
+	// Publish sends msg to every other instance serving roomID. It must not
+	// block the calling room.
+	Publish(roomID string, msg []byte) error
+	// Subscribe returns a channel delivering messages published for roomID
+	// by other instances.
+	Subscribe(roomID string) <-chan []byte
+	// Unsubscribe stops delivery for roomID and closes its channel.
+	Unsubscribe(roomID string)
+	Close() error
+}
+
+// brokerEnvelope wraps published messages so an instance can ignore its own
+// messages when they come back through the subscription.
+type brokerEnvelope struct {
+	Origin  string          `json:"origin"`
+	Message json.RawMessage `json:"message"`
+}
+
+const (
+	// redisTimeout bounds dialing Redis and every command sent to it.
+	redisTimeout = 5 * time.Second
+	// redisPublishBuffer is how many messages may wait for the publisher.
+	// Publish drops messages once it is full.
+	redisPublishBuffer = 256
+)
+
+var errPublishQueueFull = errors.New("redis publish queue is full, dropping message")
+
+// redisPublish is a message waiting to be published.
+type redisPublish struct {
+	channel string
+	payload []byte
+}
+
+// RedisBroker implements Broker on top of Redis pub/sub. Messages are
+// published from a goroutine of their own, so a slow or unreachable Redis
+// never blocks a room. The subscription reconnects and resubscribes to every
+// room on its own if the connection drops.
+type RedisBroker struct {
+	origin string
+	client *redis.Client
+	pubsub *redis.PubSub
+
+	pending chan redisPublish
+
+	subMu sync.Mutex
+	subs  map[string]chan []byte
+
+	done chan struct{}
+}
+
+// NewRedisBroker connects to the Redis server at addr and starts listening
+// for subscribed messages.
+func NewRedisBroker(addr string) *RedisBroker {
+	client := redis.NewClient(&redis.Options{
+		Addr:         addr,
+		DialTimeout:  redisTimeout,
+		ReadTimeout:  redisTimeout,
+		WriteTimeout: redisTimeout,
+	})
+	b := &RedisBroker{
+		origin:  randomHex(8),
+		client:  client,
+		pubsub:  client.Subscribe(context.Background()),
+		pending: make(chan redisPublish, redisPublishBuffer),
+		subs:    make(map[string]chan []byte),
+		done:    make(chan struct{}),
+	}
+	go b.listen()
+	go b.publishLoop()
+	return b
+}
+
+func redisChannel(roomID string) string {
+	return "spiele:room:" + roomID
+}
+
+// Publish queues msg for the publisher goroutine. It returns
+// errPublishQueueFull instead of blocking when Redis cannot keep up.
+func (b *RedisBroker) Publish(roomID string, msg []byte) error {
+	payload, err := json.Marshal(brokerEnvelope{Origin: b.origin, Message: msg})
+	if err != nil {
+		return err
+	}
+	select {
+	case b.pending <- redisPublish{channel: redisChannel(roomID), payload: payload}:
+		return nil
+	default:
+		return errPublishQueueFull
+	}
+}
+
+// publishLoop publishes queued messages until the broker is closed.
+func (b *RedisBroker) publishLoop() {
+	for {
+		select {
+		case <-b.done:
+			return
+		case msg := <-b.pending:
+			ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+			err := b.client.Publish(ctx, msg.channel, msg.payload).Err()
+			cancel()
+			if err != nil {
+				slog.Error("Error publishing to redis", slog.String("channel", msg.channel), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+func (b *RedisBroker) Subscribe(roomID string) <-chan []byte {
+	channel := redisChannel(roomID)
+
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+
+	ch, ok := b.subs[channel]
+	if !ok {
+		ch = make(chan []byte, 64)
+		b.subs[channel] = ch
+		// A failed subscribe is retried when the subscription reconnects
+		ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+		defer cancel()
+		if err := b.pubsub.Subscribe(ctx, channel); err != nil {
+			slog.Error("Error subscribing to redis channel", slog.String("channel", channel), slog.Any("error", err))
+		}
+	}
+	return ch
+}
+
+func (b *RedisBroker) Unsubscribe(roomID string) {
+	channel := redisChannel(roomID)
+
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+
+	ch, ok := b.subs[channel]
+	if !ok {
+		return
+	}
+	delete(b.subs, channel)
+	close(ch)
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := b.pubsub.Unsubscribe(ctx, channel); err != nil {
+		slog.Error("Error unsubscribing from redis channel", slog.String("channel", channel), slog.Any("error", err))
+	}
+}
+
+func (b *RedisBroker) Close() error {
+	close(b.done)
+	if err := b.pubsub.Close(); err != nil {
+		b.client.Close()
+		return err
+	}
+	return b.client.Close()
+}
+
+// listen dispatches subscribed messages to the rooms until the broker is
+// closed.
+func (b *RedisBroker) listen() {
+	for msg := range b.pubsub.Channel() {
+		var envelope brokerEnvelope
+		if err := json.Unmarshal([]byte(msg.Payload), &envelope); err != nil {
+			slog.Warn("Ignoring malformed redis message", slog.String("channel", msg.Channel), slog.Any("error", err))
+			continue
+		}
+		if envelope.Origin == b.origin {
+			continue
+		}
+
+		b.subMu.Lock()
+		if ch, ok := b.subs[msg.Channel]; ok {
+			select {
+			case ch <- envelope.Message:
+			default:
+				slog.Warn("Dropping redis message for slow room", slog.String("channel", msg.Channel))
+			}
+		}
+		b.subMu.Unlock()
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestRedisBrokerPublishDropsWhenFull(t *testing.T) {
+	b := &RedisBroker{origin: "test", pending: make(chan redisPublish, 1)}
+	if err := b.Publish("a", []byte(`{"type":"chat"}`)); err != nil {
+		t.Fatalf("first Publish: %v", err)
+	}
+	if err := b.Publish("a", []byte(`{"type":"chat"}`)); err != errPublishQueueFull {
+		t.Fatalf("second Publish error = %v, want errPublishQueueFull", err)
+	}
+	msg := <-b.pending
+	if msg.channel != redisChannel("a") {
+		t.Errorf("queued channel = %q, want %q", msg.channel, redisChannel("a"))
+	}
+}
+
+// waitForSubscribers waits until n connections subscribed to the channel of
+// roomID.
+func waitForSubscribers(t *testing.T, mr *miniredis.Miniredis, roomID string, n int) {
+	t.Helper()
+	channel := redisChannel(roomID)
+	deadline := time.Now().Add(5 * time.Second)
+	for mr.PubSubNumSub(channel)[channel] != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s has %d subscribers, want %d", channel, mr.PubSubNumSub(channel)[channel], n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// receive waits for the next message on ch.
+func receive(t *testing.T, ch <-chan []byte) string {
+	t.Helper()
+	select {
+	case msg, ok := <-ch:
+		if !ok {
+			t.Fatal("subscription channel closed")
+		}
+		return string(msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+		return ""
+	}
+}
+
+func TestRedisBroker(t *testing.T) {
+	mr := miniredis.RunT(t)
+	local := NewRedisBroker(mr.Addr())
+	defer local.Close()
+	other := NewRedisBroker(mr.Addr())
+	defer other.Close()
+
+	ch := local.Subscribe("room")
+	waitForSubscribers(t, mr, "room", 1)
+
+	// Messages published by the subscribing instance itself are skipped
+	if err := local.Publish("room", []byte(`{"type":"own"}`)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if err := other.Publish("room", []byte(`{"type":"chat"}`)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if got := receive(t, ch); got != `{"type":"chat"}` {
+		t.Errorf("received %s, want the other instance's chat", got)
+	}
+
+	local.Unsubscribe("room")
+	if _, ok := <-ch; ok {
+		t.Error("channel still open after Unsubscribe")
+	}
+	waitForSubscribers(t, mr, "room", 0)
+}
+
+// The broker resubscribes to its rooms once Redis is back.
+func TestRedisBrokerResubscribes(t *testing.T) {
+	mr := miniredis.RunT(t)
+	local := NewRedisBroker(mr.Addr())
+	defer local.Close()
+	other := NewRedisBroker(mr.Addr())
+	defer other.Close()
+
+	ch := local.Subscribe("room")
+	waitForSubscribers(t, mr, "room", 1)
+
+	mr.Close()
+	if err := mr.Restart(); err != nil {
+		t.Fatalf("Restart: %v", err)
+	}
+	waitForSubscribers(t, mr, "room", 1)
+
+	if err := other.Publish("room", []byte(`{"type":"chat"}`)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if got := receive(t, ch); got != `{"type":"chat"}` {
+		t.Errorf("received %s after the restart, want the chat", got)
+	}
+}
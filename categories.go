@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// CategoryEntry is a single category together with the tags clients can use
+// to narrow down which categories are drawn.
+type CategoryEntry struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+// UnmarshalJSON also accepts a plain string so untagged category lists keep
+// working, e.g. for PUT /admin/categories.
+func (c *CategoryEntry) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*c = CategoryEntry{Name: name}
+		return nil
+	}
+
+	type entry CategoryEntry
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return err
+	}
+	*c = CategoryEntry(e)
+	return nil
+}
+
+// hasTags reports whether the entry carries every one of the given tags.
+func (c CategoryEntry) hasTags(tags []string) bool {
+	for _, tag := range tags {
+		if !contains(c.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeTags lowercases and trims tags and drops empty and duplicate ones.
+func normalizeTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || contains(result, tag) {
+			continue
+		}
+		result = append(result, tag)
+	}
+	return result
+}
+
+// parseTags extracts the optional "tags" array from a client message.
+// Non-string elements are ignored.
+func parseTags(value interface{}) []string {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	tags := make([]string, 0, len(list))
+	for _, item := range list {
+		if tag, ok := item.(string); ok {
+			tags = append(tags, tag)
+		}
+	}
+	return normalizeTags(tags)
+}
+
+// readCategories reads and validates Config.CategoriesFile, falling back to
+// the embedded data/categories.json. The file maps pack names to their
+// categories and must contain the default pack.
+func (s *Server) readCategories() (map[string][]CategoryEntry, error) {
+	var raw []byte
+	var err error
+	if s.config.CategoriesFile != "" {
+		raw, err = os.ReadFile(s.config.CategoriesFile)
+	} else {
+		raw, err = data.ReadFile("data/categories.json")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var packs map[string][]CategoryEntry
+	if err := json.Unmarshal(raw, &packs); err != nil {
+		return nil, err
+	}
+	for name, categories := range packs {
+		packs[name] = dedupeCategories(categories)
+		if len(packs[name]) == 0 {
+			return nil, fmt.Errorf("category pack %q is empty", name)
+		}
+	}
+	if _, ok := packs[defaultPack]; !ok {
+		return nil, fmt.Errorf("category pack %q is missing", defaultPack)
+	}
+	return packs, nil
+}
+
+// ReloadCategories swaps in freshly read category packs. New rooms use them
+// right away, running rooms from their next round on. The current packs are
+// kept if the file is invalid.
+func (s *Server) ReloadCategories() error {
+	packs, err := s.readCategories()
+	if err != nil {
+		return err
+	}
+
+	s.categoriesMu.Lock()
+	s.categoryPacks = packs
+	s.categoriesMu.Unlock()
+	slog.Info("Reloaded categories", slog.Int("packs", len(packs)), slog.Int("count", len(packs[defaultPack])))
+	return nil
+}
+
+// setCategoryPack replaces or adds a single pack.
+func (s *Server) setCategoryPack(pack string, categories []CategoryEntry) {
+	s.categoriesMu.Lock()
+	defer s.categoriesMu.Unlock()
+
+	// Copy the map so readers holding the old one are unaffected
+	packs := make(map[string][]CategoryEntry, len(s.categoryPacks)+1)
+	for name, list := range s.categoryPacks {
+		packs[name] = list
+	}
+	packs[pack] = categories
+	s.categoryPacks = packs
+}
+
+// Ready reports whether the server can host games: the categories are loaded
+// and the default pack is not empty.
+func (s *Server) Ready() bool {
+	categories, ok := s.getCategoryPack(defaultPack)
+	return ok && len(categories) > 0
+}
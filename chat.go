@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+// sendChat relays a free text message to the room. Chat is purely social:
+// it ends up in the event log but never touches the game state or the
+// round timer.
+func (r *Room) sendChat(conn *websocket.Conn, msg map[string]interface{}) {
+	text, _ := msg["text"].(string)
+	if strings.TrimSpace(text) == "" {
+		r.rejectMessage(conn, &InvalidMessageError{Field: "text", Reason: "empty"})
+		return
+	}
+	if utf8.RuneCountInString(text) > r.server.config.MaxChatLength {
+		r.rejectMessage(conn, &InvalidMessageError{Field: "text", Reason: "tooLong"})
+		return
+	}
+
+	broadcastMsg, err := newBroadcast(conn, map[string]interface{}{
+		"type": "chat",
+		"text": text,
+	})
+	if err != nil {
+		slog.Error("Error marshalling chat message", slog.String("room", r.id), remoteAttr(conn), slog.Any("error", err))
+		return
+	}
+	// Chat is relayed to the peers like any other client message
+	broadcastMsg.includeSender = false
+	broadcastMsg.seq, broadcastMsg.ack = parseSeq(msg["seq"])
+	broadcastMsg.timestamp = time.Now()
+	enqueue(r, r.broadcast, broadcastMsg)
+	r.logEvent("chat", map[string]interface{}{
+		"clientId": r.clientID(conn),
+		"text":     text,
+	})
+}
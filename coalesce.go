@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// coalescible reports whether a message may wait for Config.CoalesceWindow
+// to be sent along with others. Only relayed client messages qualify;
+// messages the server generates are sent immediately.
+func (r *Room) coalescible(broadcastMsg BroadcastMessage, binary bool) bool {
+	return r.server.config.CoalesceWindow > 0 &&
+		!binary &&
+		!broadcastMsg.includeSender
+}
+
+// coalesce adds a message to the pending batch, starting the window if it is
+// the first one. It runs in room.run().
+func (r *Room) coalesce(broadcastMsg BroadcastMessage) {
+	r.coalesced = append(r.coalesced, broadcastMsg)
+	if r.coalesceFlush == nil {
+		r.coalesceTimer = time.NewTimer(r.server.config.CoalesceWindow)
+		r.coalesceFlush = r.coalesceTimer.C
+	}
+}
+
+// flushCoalesced writes the pending batch with a single frame per
+// connection. Each player gets the messages sent by the others. It runs in
+// room.run().
+func (r *Room) flushCoalesced() {
+	if len(r.coalesced) == 0 {
+		return
+	}
+	pending := r.coalesced
+	r.coalesced = nil
+	r.coalesceTimer.Stop()
+	r.coalesceFlush = nil
+
+	// Senders whose messages did not reach every peer get a nack
+	failed := make(map[*websocket.Conn]bool)
+	for client := range r.clients {
+		if client == nil || r.inGrace(client) {
+			continue
+		}
+		batch := make([]BroadcastMessage, 0, len(pending))
+		for _, broadcastMsg := range pending {
+			if broadcastMsg.sender != client {
+				batch = append(batch, broadcastMsg)
+			}
+		}
+		if len(batch) == 0 {
+			continue
+		}
+		if err := r.writeMessage(client, websocket.TextMessage, r.batchFrame(batch)); err != nil {
+			r.dropClient(client, err)
+			for _, broadcastMsg := range batch {
+				failed[broadcastMsg.sender] = true
+			}
+		}
+	}
+	r.writeSpectators(websocket.TextMessage, r.batchFrame(pending))
+
+	for _, broadcastMsg := range pending {
+		if broadcastMsg.ack && r.clients[broadcastMsg.sender] {
+			r.sendAck(broadcastMsg.sender, broadcastMsg.seq, !failed[broadcastMsg.sender])
+		}
+		r.publish(broadcastMsg)
+	}
+	r.announceDropped()
+}
+
+// batchFrame encodes messages as a JSON array. A single message is sent as
+// is, so clients only see arrays when messages were actually batched.
+func (r *Room) batchFrame(batch []BroadcastMessage) []byte {
+	if len(batch) == 1 {
+		return batch[0].message
+	}
+	messages := make([]json.RawMessage, len(batch))
+	for i, broadcastMsg := range batch {
+		messages[i] = broadcastMsg.message
+	}
+	frame, err := json.Marshal(messages)
+	if err != nil {
+		slog.Error("Error encoding message batch", slog.String("room", r.id), slog.Any("error", err))
+	}
+	return frame
+}
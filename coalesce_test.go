@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBatchFrame(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []string
+		want     string
+	}{
+		{"single message is sent as is", []string{`{"type":"chat"}`}, `{"type":"chat"}`},
+		{"two messages", []string{`{"type":"chat"}`, `{"type":"typing"}`}, `[{"type":"chat"},{"type":"typing"}]`},
+		{"order is kept", []string{`{"n":3}`, `{"n":1}`, `{"n":2}`}, `[{"n":3},{"n":1},{"n":2}]`},
+		{"whitespace is compacted", []string{`{ "n": 1 }`, `{"n":2}`}, `[{"n":1},{"n":2}]`},
+	}
+	r := &Room{id: "test"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch := make([]BroadcastMessage, len(tt.messages))
+			for i, message := range tt.messages {
+				batch[i] = BroadcastMessage{message: []byte(message)}
+			}
+			got := r.batchFrame(batch)
+			if string(got) != tt.want {
+				t.Errorf("batchFrame = %s, want %s", got, tt.want)
+			}
+			if !json.Valid(got) {
+				t.Errorf("batchFrame produced invalid JSON %s", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultConfig returns the configuration used when nothing overrides it.
+func defaultConfig() Config {
+	return Config{
+		Port:                    "8080",
+		MaxClients:              8,
+		MaxSpectators:           10,
+		CleanupInterval:         5 * time.Minute,
+		RoomTimeout:             30 * time.Minute,
+		CleanupStrategy:         "both",
+		ReadTimeout:             10 * time.Second,
+		WriteTimeout:            10 * time.Second,
+		ReconnectWindow:         30 * time.Second,
+		MaxMessagesPerSecond:    10,
+		MaxMessageBytes:         4096,
+		LogLevel:                "info",
+		HistorySize:             20,
+		MaxChatLength:           500,
+		MaxPointsPerRound:       100,
+		RoundDuration:           60 * time.Second,
+		AutoStartTimer:          true,
+		RedirectPort:            "80",
+		MaxQueueDepth:           5,
+		RoomChannelBuffer:       64,
+		SlowSendThreshold:       10 * time.Millisecond,
+		LockTimeout:             10 * time.Second,
+		KickVoteTimeout:         time.Minute,
+		PostGameTimeout:         5 * time.Minute,
+		PongTimeout:             10 * time.Second,
+		MaxMessageAge:           2 * time.Second,
+		EventLogSize:            1000,
+		TrackCategoryUsage:      true,
+		DisconnectGrace:         5 * time.Second,
+		HeartbeatInterval:       defaultHeartbeat,
+		CoalesceWindow:          5 * time.Millisecond,
+		RoomIDMinLength:         4,
+		RoomIDMaxLength:         32,
+		ClientInactivityTimeout: 5 * time.Minute,
+	}
+}
+
+// NewConfigFromEnv returns the default configuration with the SPIELE_*
+// environment variables applied. See applyEnv.
+func NewConfigFromEnv() (Config, []string, error) {
+	return applyEnv(defaultConfig())
+}
+
+// LoadConfigFile reads a JSON config file on top of the defaults. Fields
+// missing from the file keep their default value.
+func LoadConfigFile(path string) (Config, error) {
+	config := defaultConfig()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return Config{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// applyEnv applies the SPIELE_* environment variables to config. It also
+// returns the names of the variables that took effect. Invalid values keep
+// the previous value and are reported in the error.
+func applyEnv(config Config) (Config, []string, error) {
+	env := envReader{}
+	env.string("SPIELE_PORT", &config.Port)
+	env.int("SPIELE_MAX_CLIENTS", &config.MaxClients)
+	env.duration("SPIELE_CLEANUP_INTERVAL", &config.CleanupInterval)
+	env.duration("SPIELE_ROOM_TIMEOUT", &config.RoomTimeout)
+	env.duration("SPIELE_READ_TIMEOUT", &config.ReadTimeout)
+	env.duration("SPIELE_WRITE_TIMEOUT", &config.WriteTimeout)
+	env.string("SPIELE_ADMIN_TOKEN", &config.AdminToken)
+	env.string("SPIELE_REDIS_ADDR", &config.RedisAddr)
+	return config, env.overridden, errors.Join(env.errs...)
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// UnmarshalJSON accepts durations as Go duration strings such as "30s" in
+// addition to nanoseconds.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	t := reflect.TypeOf(*c)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Type != durationType {
+			continue
+		}
+		name := configFieldName(field)
+		var value string
+		if json.Unmarshal(raw[name], &value) != nil {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		raw[name], _ = json.Marshal(int64(d))
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	type config Config
+	return json.Unmarshal(data, (*config)(c))
+}
+
+// secretConfigFields are masked in the config summary.
+var secretConfigFields = map[string]bool{
+	"adminToken":    true,
+	"webhookSecret": true,
+}
+
+// Summary lists every config field with its value, one per line. Secrets are
+// only reported as set or not.
+func (c Config) Summary() string {
+	var b strings.Builder
+	v := reflect.ValueOf(c)
+	for i := 0; i < v.NumField(); i++ {
+		name := configFieldName(v.Type().Field(i))
+		value := v.Field(i).Interface()
+		if secretConfigFields[name] {
+			value = "(not set)"
+			if v.Field(i).String() != "" {
+				value = "(set)"
+			}
+		}
+		fmt.Fprintf(&b, "%-24s %v\n", name, value)
+	}
+	return b.String()
+}
+
+// configFieldName returns the JSON name of a Config field.
+func configFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
+
+// envReader applies environment variables to config fields and remembers
+// which ones it applied and which ones were invalid.
+type envReader struct {
+	overridden []string
+	errs       []error
+}
+
+func (e *envReader) string(name string, field *string) {
+	if value, ok := os.LookupEnv(name); ok && value != "" {
+		*field = value
+		e.overridden = append(e.overridden, name)
+	}
+}
+
+func (e *envReader) int(name string, field *int) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		e.errs = append(e.errs, fmt.Errorf("%s must be a positive integer, got %q", name, value))
+		return
+	}
+	*field = n
+	e.overridden = append(e.overridden, name)
+}
+
+func (e *envReader) duration(name string, field *time.Duration) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		e.errs = append(e.errs, fmt.Errorf("%s must be a positive duration such as 30s, got %q", name, value))
+		return
+	}
+	*field = d
+	e.overridden = append(e.overridden, name)
+}
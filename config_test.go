@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		check   func(Config) bool
+		wantErr string
+	}{
+		{
+			name:  "duration string",
+			input: `{"roundDuration": "90s"}`,
+			check: func(c Config) bool { return c.RoundDuration == 90*time.Second },
+		},
+		{
+			name:  "duration nanoseconds",
+			input: `{"roundDuration": 2000000000}`,
+			check: func(c Config) bool { return c.RoundDuration == 2*time.Second },
+		},
+		{
+			name:  "compound duration",
+			input: `{"roomTimeout": "1h30m"}`,
+			check: func(c Config) bool { return c.RoomTimeout == 90*time.Minute },
+		},
+		{
+			name:  "other fields",
+			input: `{"port": "9000", "maxClients": 4}`,
+			check: func(c Config) bool { return c.Port == "9000" && c.MaxClients == 4 },
+		},
+		{
+			name:  "missing fields keep defaults",
+			input: `{"port": "9000"}`,
+			check: func(c Config) bool {
+				return c.RoundDuration == defaultConfig().RoundDuration && c.MaxClients == defaultConfig().MaxClients
+			},
+		},
+		{
+			name:    "invalid duration",
+			input:   `{"readTimeout": "soon"}`,
+			wantErr: "readTimeout",
+		},
+		{
+			name:    "wrong type",
+			input:   `{"maxClients": "four"}`,
+			wantErr: "cannot unmarshal",
+		},
+		{
+			name:    "not an object",
+			input:   `[]`,
+			wantErr: "cannot unmarshal",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := defaultConfig()
+			err := json.Unmarshal([]byte(tt.input), &config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Unmarshal error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if !tt.check(config) {
+				t.Errorf("Unmarshal(%s) gave unexpected config %+v", tt.input, config)
+			}
+		})
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name           string
+		env            map[string]string
+		check          func(Config) bool
+		wantOverridden []string
+		wantErr        string
+	}{
+		{
+			name:  "nothing set",
+			check: func(c Config) bool { return reflect.DeepEqual(c, defaultConfig()) },
+		},
+		{
+			name:           "string",
+			env:            map[string]string{"SPIELE_PORT": "9000"},
+			check:          func(c Config) bool { return c.Port == "9000" },
+			wantOverridden: []string{"SPIELE_PORT"},
+		},
+		{
+			name:           "int",
+			env:            map[string]string{"SPIELE_MAX_CLIENTS": "3"},
+			check:          func(c Config) bool { return c.MaxClients == 3 },
+			wantOverridden: []string{"SPIELE_MAX_CLIENTS"},
+		},
+		{
+			name:           "duration",
+			env:            map[string]string{"SPIELE_ROOM_TIMEOUT": "45m"},
+			check:          func(c Config) bool { return c.RoomTimeout == 45*time.Minute },
+			wantOverridden: []string{"SPIELE_ROOM_TIMEOUT"},
+		},
+		{
+			name:  "empty value is ignored",
+			env:   map[string]string{"SPIELE_PORT": ""},
+			check: func(c Config) bool { return c.Port == defaultConfig().Port },
+		},
+		{
+			name:    "invalid int keeps default",
+			env:     map[string]string{"SPIELE_MAX_CLIENTS": "many"},
+			check:   func(c Config) bool { return c.MaxClients == defaultConfig().MaxClients },
+			wantErr: "SPIELE_MAX_CLIENTS",
+		},
+		{
+			name:    "non-positive int",
+			env:     map[string]string{"SPIELE_MAX_CLIENTS": "0"},
+			check:   func(c Config) bool { return c.MaxClients == defaultConfig().MaxClients },
+			wantErr: "SPIELE_MAX_CLIENTS",
+		},
+		{
+			name:    "invalid duration keeps default",
+			env:     map[string]string{"SPIELE_READ_TIMEOUT": "10"},
+			check:   func(c Config) bool { return c.ReadTimeout == defaultConfig().ReadTimeout },
+			wantErr: "SPIELE_READ_TIMEOUT",
+		},
+		{
+			name:           "valid and invalid mixed",
+			env:            map[string]string{"SPIELE_PORT": "9000", "SPIELE_WRITE_TIMEOUT": "-1s"},
+			check:          func(c Config) bool { return c.Port == "9000" && c.WriteTimeout == defaultConfig().WriteTimeout },
+			wantOverridden: []string{"SPIELE_PORT"},
+			wantErr:        "SPIELE_WRITE_TIMEOUT",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"SPIELE_PORT", "SPIELE_MAX_CLIENTS", "SPIELE_CLEANUP_INTERVAL", "SPIELE_ROOM_TIMEOUT", "SPIELE_READ_TIMEOUT", "SPIELE_WRITE_TIMEOUT", "SPIELE_ADMIN_TOKEN", "SPIELE_REDIS_ADDR"} {
+				t.Setenv(name, "")
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			config, overridden, err := applyEnv(defaultConfig())
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("applyEnv: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("applyEnv error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(overridden, tt.wantOverridden) {
+				t.Errorf("applyEnv overridden = %v, want %v", overridden, tt.wantOverridden)
+			}
+			if !tt.check(config) {
+				t.Errorf("applyEnv gave unexpected config %+v", config)
+			}
+		})
+	}
+}
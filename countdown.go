@@ -0,0 +1,89 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// With Config.AutoStartCountdown set, a room that fills up counts down
+// before the game starts. The room stays in StateWaiting meanwhile, so game
+// messages are still rejected.
+
+// startCountdown announces the countdown and starts ticking it down once a
+// second. It runs in room.run().
+func (r *Room) startCountdown() {
+	r.stopCountdown()
+	r.countdownLeft = int(math.Ceil(r.server.config.AutoStartCountdown.Seconds()))
+	r.countdown = time.NewTicker(time.Second)
+	r.countdownTick = r.countdown.C
+	slog.Info("Countdown started", slog.String("room", r.id), slog.Int("seconds", r.countdownLeft))
+	r.announceCountdown()
+}
+
+// handleCountdownTick counts down one second and starts the game once the
+// countdown reached zero. It runs in room.run().
+func (r *Room) handleCountdownTick() {
+	r.countdownLeft--
+	if r.countdownLeft > 0 {
+		r.announceCountdown()
+		return
+	}
+	r.stopCountdown()
+
+	r.mu.Lock()
+	started := r.transitionLocked(StatePlaying)
+	if started && r.server.config.AutoStartTimer {
+		r.startRoundTimerLocked()
+	}
+	r.mu.Unlock()
+	if started {
+		r.announceGameStart()
+	}
+}
+
+// announceCountdown tells every player how many seconds are left. It runs in
+// room.run().
+func (r *Room) announceCountdown() {
+	broadcastMsg, err := newBroadcast(nil, map[string]interface{}{
+		"type":    "countdown",
+		"seconds": r.countdownLeft,
+	})
+	if err != nil {
+		slog.Error("Error marshalling countdown message", slog.String("room", r.id), slog.Any("error", err))
+		return
+	}
+	r.broadcastMessage(broadcastMsg)
+}
+
+// cancelCountdown stops a running countdown and tells the players why. The
+// room keeps waiting; the countdown starts over once it fills up again. It
+// runs in room.run().
+func (r *Room) cancelCountdown(sender *websocket.Conn, reason string) {
+	if r.countdownTick == nil {
+		return
+	}
+	r.stopCountdown()
+
+	slog.Info("Countdown cancelled", slog.String("room", r.id), slog.String("reason", reason))
+	broadcastMsg, err := newBroadcast(sender, map[string]interface{}{
+		"type":   "countdownCancelled",
+		"reason": reason,
+	})
+	if err != nil {
+		slog.Error("Error marshalling countdownCancelled message", slog.String("room", r.id), slog.Any("error", err))
+		return
+	}
+	r.broadcastMessage(broadcastMsg)
+}
+
+// stopCountdown stops the countdown ticker. It runs in room.run().
+func (r *Room) stopCountdown() {
+	if r.countdown != nil {
+		r.countdown.Stop()
+	}
+	r.countdown = nil
+	r.countdownTick = nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// With Config.EnableDeduplication, a player whose browser already holds a
+// slot in the room, e.g. from a second tab, is turned away. Browsers are
+// told apart by a fingerprint of the upgrade request. It is a heuristic:
+// players behind the same address with the same browser and language look
+// alike, and a player can evade it by changing any of them.
+
+// connFingerprint hashes the client address, User-Agent and
+// Accept-Language of an upgrade request.
+func connFingerprint(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	sum := sha256.Sum256([]byte(host + "\x00" + r.UserAgent() + "\x00" + r.Header.Get("Accept-Language")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// setFingerprint remembers the fingerprint of a connection.
+func (r *Room) setFingerprint(conn *websocket.Conn, fingerprint string) {
+	r.connsMu.Lock()
+	r.fingerprints[conn] = fingerprint
+	r.connsMu.Unlock()
+}
+
+// forgetFingerprint drops the fingerprint once the room is done with conn.
+func (r *Room) forgetFingerprint(conn *websocket.Conn) {
+	r.connsMu.Lock()
+	delete(r.fingerprints, conn)
+	r.connsMu.Unlock()
+}
+
+// isDuplicate reports whether another connected player has the same
+// fingerprint as conn. Players in their grace period are not counted, as
+// they may be reconnecting from a new tab. It runs in room.run().
+func (r *Room) isDuplicate(conn *websocket.Conn) bool {
+	if !r.server.config.EnableDeduplication {
+		return false
+	}
+	r.connsMu.Lock()
+	defer r.connsMu.Unlock()
+	fingerprint, ok := r.fingerprints[conn]
+	if !ok {
+		return false
+	}
+	for client := range r.clients {
+		if client != conn && !r.inGrace(client) && r.fingerprints[client] == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectDuplicate turns away a connection that duplicates a player already
+// in the room. It runs in room.run().
+func (r *Room) rejectDuplicate(conn *websocket.Conn) {
+	r.connLogger(conn).Warn("Duplicate connection, rejecting new client", slog.String("room", r.id), remoteAttr(conn))
+	r.server.metrics.countError("duplicateConnection")
+	if err := r.writeJSON(conn, map[string]interface{}{
+		"type": "duplicateConnection",
+	}); err != nil {
+		r.connLogger(conn).Error("Error sending duplicateConnection message", slog.String("room", r.id), remoteAttr(conn), slog.Any("error", err))
+	}
+	conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "duplicate connection"),
+		time.Now().Add(time.Second),
+	)
+	conn.Close()
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// RoomFullError is returned when a room has no free player slot and the join
+// queue is disabled.
+type RoomFullError struct {
+	RoomID     string
+	MaxClients int
+}
+
+func (e *RoomFullError) Error() string {
+	return fmt.Sprintf("room %s is full (%d players)", e.RoomID, e.MaxClients)
+}
+
+// SpectatorsFullError is returned when a room has no free spectator slot.
+type SpectatorsFullError struct {
+	RoomID        string
+	MaxSpectators int
+}
+
+func (e *SpectatorsFullError) Error() string {
+	return fmt.Sprintf("room %s has no spectator slots left (%d spectators)", e.RoomID, e.MaxSpectators)
+}
+
+// UnknownPackError is returned when a room is created with a category pack
+// the server does not have.
+type UnknownPackError struct {
+	Pack string
+}
+
+func (e *UnknownPackError) Error() string {
+	return fmt.Sprintf("unknown category pack %q", e.Pack)
+}
+
+// InvalidMessageError describes a client message that failed validation.
+type InvalidMessageError struct {
+	Field  string
+	Reason string
+}
+
+func (e *InvalidMessageError) Error() string {
+	return fmt.Sprintf("invalid message: %s is %s", e.Field, e.Reason)
+}
+
+// CategoryExhaustedError is returned when every category matching the
+// requested tags has been drawn.
+type CategoryExhaustedError struct {
+	Tags []string
+}
+
+func (e *CategoryExhaustedError) Error() string {
+	return fmt.Sprintf("no categories left for tags %v", e.Tags)
+}
+
+// errorCode maps an error to the code sent to clients.
+func errorCode(err error) string {
+	var (
+		roomFull       *RoomFullError
+		spectatorsFull *SpectatorsFullError
+		unknownPack    *UnknownPackError
+		invalid        *InvalidMessageError
+		exhausted      *CategoryExhaustedError
+	)
+	switch {
+	case errors.As(err, &roomFull):
+		return "roomFull"
+	case errors.As(err, &spectatorsFull):
+		return "spectatorsFull"
+	case errors.As(err, &unknownPack):
+		return "unknownPack"
+	case errors.As(err, &invalid):
+		return "invalidMessage"
+	case errors.As(err, &exhausted):
+		return "noMoreCategories"
+	default:
+		return "internal"
+	}
+}
+
+// rejectConnection tells a client why it cannot join and closes the
+// connection with a policy violation. It is only used before the
+// connection's reader goroutine starts, so the write needs no lock.
+func rejectConnection(conn *websocket.Conn, err error) {
+	message, reason := rejection(err)
+	closeConnection(conn, message, reason)
+}
+
+// rejectRegistered is rejectConnection for a connection whose reader
+// goroutine is already running. It must only be called from room.run().
+func (r *Room) rejectRegistered(conn *websocket.Conn, err error) {
+	message, reason := rejection(err)
+	if err := r.writeMessage(conn, websocket.TextMessage, message); err != nil {
+		slog.Error("Error sending connection rejection", slog.String("room", r.id), remoteAttr(conn), slog.Any("error", err))
+	}
+	closePolicyViolation(conn, reason)
+}
+
+// rejection returns the message telling a client why it cannot join and the
+// reason for the close frame.
+func rejection(err error) ([]byte, string) {
+	var roomFull *RoomFullError
+	if errors.As(err, &roomFull) {
+		message, _ := json.Marshal(roomFullMessage(roomFull.RoomID))
+		return message, "room full"
+	}
+
+	message, _ := json.Marshal(map[string]interface{}{
+		"type":    "error",
+		"code":    errorCode(err),
+		"message": err.Error(),
+	})
+	return message, errorCode(err)
+}
+
+// roomFullMessage is sent to a client turned away from a full room, so it
+// can tell the rejection from a network error.
+func roomFullMessage(roomID string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":   "roomFull",
+		"roomId": roomID,
+	}
+}
+
+// closeConnection writes message, then closes the connection with a policy
+// violation close frame carrying reason.
+func closeConnection(conn *websocket.Conn, message []byte, reason string) {
+	if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+		slog.Error("Error sending connection rejection", remoteAttr(conn), slog.Any("error", err))
+	}
+	closePolicyViolation(conn, reason)
+}
+
+// closePolicyViolation sends a policy violation close frame carrying reason
+// and closes the connection. WriteControl may be called concurrently with
+// other writes.
+func closePolicyViolation(conn *websocket.Conn, reason string) {
+	conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason),
+		time.Now().Add(time.Second),
+	)
+	conn.Close()
+}
+
+// handleMessageError reports an error returned while handling a message to
+// the sender in the form the protocol expects for it.
+func (r *Room) handleMessageError(conn *websocket.Conn, msgType MessageType, err error) {
+	var (
+		invalid   *InvalidMessageError
+		exhausted *CategoryExhaustedError
+	)
+	switch {
+	case errors.As(err, &invalid):
+		r.rejectMessage(conn, invalid)
+	case errors.As(err, &exhausted):
+		if err := r.writeJSON(conn, map[string]interface{}{
+			"type": "noMoreCategories",
+			"tags": exhausted.Tags,
+		}); err != nil {
+			slog.Error("Error sending noMoreCategories message", slog.String("room", r.id), remoteAttr(conn), slog.Any("error", err))
+		}
+	default:
+		slog.Error("Error handling message", slog.String("room", r.id), remoteAttr(conn), slog.String("type", string(msgType)), slog.Any("error", err))
+		r.logEvent("messageError", map[string]interface{}{
+			"clientId": r.clientID(conn),
+			"msgType":  msgType,
+			"error":    err.Error(),
+		})
+		if err := r.writeJSON(conn, map[string]interface{}{
+			"type": "error",
+			"code": errorCode(err),
+		}); err != nil {
+			slog.Error("Error sending error message", slog.String("room", r.id), remoteAttr(conn), slog.Any("error", err))
+		}
+	}
+}
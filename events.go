@@ -0,0 +1,55 @@
+package main
+
+import "time"
+
+// RoomEvent is an entry of a room's event log.
+type RoomEvent struct {
+	Time    time.Time              `json:"time"`
+	Type    string                 `json:"type"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// logEvent appends an event to the room's event log.
+func (r *Room) logEvent(eventType string, details map[string]interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logEventLocked(eventType, details)
+}
+
+// logEventLocked appends an event, overwriting the oldest one once
+// Config.EventLogSize events are stored. r.mu must be held.
+func (r *Room) logEventLocked(eventType string, details map[string]interface{}) {
+	size := r.server.config.EventLogSize
+	if size <= 0 {
+		return
+	}
+
+	// Name the client so the log is readable without the ID mapping
+	if clientID, ok := details["clientId"].(string); ok {
+		if name, ok := r.clientNames[clientID]; ok {
+			details["name"] = name
+		}
+	}
+	event := RoomEvent{Time: time.Now(), Type: eventType, Details: details}
+	if len(r.eventLog) < size {
+		r.eventLog = append(r.eventLog, event)
+		return
+	}
+	r.eventLog[r.eventStart] = event
+	r.eventStart = (r.eventStart + 1) % len(r.eventLog)
+}
+
+// eventsSince returns the logged events after since, oldest first.
+func (r *Room) eventsSince(since time.Time) []RoomEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := make([]RoomEvent, 0, len(r.eventLog))
+	for i := range r.eventLog {
+		event := r.eventLog[(r.eventStart+i)%len(r.eventLog)]
+		if event.Time.After(since) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// handleGameEnd ends the game early on the host's request. It runs in
+// room.run().
+func (r *Room) handleGameEnd(conn *websocket.Conn) {
+	if !r.clients[conn] {
+		return
+	}
+	r.mu.Lock()
+	if r.state != StatePlaying {
+		r.mu.Unlock()
+		return
+	}
+	payloads := r.endGameLocked(r.round)
+	r.mu.Unlock()
+
+	slog.Info("Game ended by host", slog.String("room", r.id), slog.String("client", r.clientIDs[conn]))
+	r.persistState()
+	r.broadcastPayloads(conn, payloads)
+}
+
+// endGameLocked moves the room to StateEnded and returns the "gameEnd"
+// message with the final scores, followed by the "gameSummary". The room
+// stays frozen for new players and is removed after Config.PostGameTimeout
+// unless the game is reset. It runs in room.run() with r.mu held.
+func (r *Room) endGameLocked(totalRounds int) []map[string]interface{} {
+	r.stopRoundTimerLocked()
+	r.transitionLocked(StateEnded)
+
+	scores := make(map[string]int, len(r.scores))
+	for clientID, total := range r.scores {
+		scores[clientID] = total
+	}
+	winner := r.winnerLocked()
+	summary := r.gameSummaryLocked(totalRounds, winner)
+	r.logEventLocked("gameEnded", map[string]interface{}{
+		"rounds": totalRounds,
+		"scores": scores,
+		"winner": winner,
+	})
+
+	// Stay locked until "resetGame" rather than for Config.LockTimeout
+	if r.lockTimer != nil {
+		r.lockTimer.Stop()
+		r.lockTimer = nil
+	}
+	r.locked = true
+	r.stopPostGameTimer()
+	if timeout := r.server.config.PostGameTimeout; timeout > 0 {
+		r.postGameTimer = time.AfterFunc(timeout, func() {
+			select {
+			case r.broadcast <- BroadcastMessage{msgType: TypePostGameTimeout, control: true}:
+			case <-r.ctx.Done():
+			}
+		})
+	}
+
+	return []map[string]interface{}{{
+		"type":        "gameEnd",
+		"finalScores": scores,
+		"winner":      winner,
+		"totalRounds": totalRounds,
+	}, summary}
+}
+
+// roundSummary describes one round of a finished game.
+type roundSummary struct {
+	Round      int            `json:"round"`
+	Categories []string       `json:"categories"`
+	Scores     map[string]int `json:"scores"`
+}
+
+// detailInt reads a number from event details. Events logged by this
+// process hold an int; events restored from the store were decoded from
+// JSON and hold a float64.
+func detailInt(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+// detailScores reads points per client from event details, in either the
+// logged or the restored form, see detailInt.
+func detailScores(value interface{}) map[string]int {
+	switch v := value.(type) {
+	case map[string]int:
+		return v
+	case map[string]interface{}:
+		scores := make(map[string]int, len(v))
+		for clientID, points := range v {
+			scores[clientID] = detailInt(points)
+		}
+		return scores
+	default:
+		return nil
+	}
+}
+
+// gameSummaryLocked builds the "gameSummary" message from the event log:
+// the categories and points of every round since the game started or was
+// last reset, how long the game took and how many categories were skipped.
+// Events already overwritten in the log are missing from it. When the host
+// ended the game early, the round still running is included with the points
+// scored so far. r.mu must be held.
+func (r *Room) gameSummaryLocked(totalRounds int, topScorer string) map[string]interface{} {
+	events := make([]RoomEvent, 0, len(r.eventLog))
+	for i := range r.eventLog {
+		event := r.eventLog[(r.eventStart+i)%len(r.eventLog)]
+		if event.Type == "gameStarted" || event.Type == "gameReset" {
+			events = events[:0]
+		}
+		events = append(events, event)
+	}
+
+	started := r.createdAt
+	if len(events) > 0 {
+		started = events[0].Time
+	}
+	rounds := make([]roundSummary, 0, totalRounds)
+	var categories []string
+	skipped := 0
+	for _, event := range events {
+		switch event.Type {
+		case "categorySelected":
+			if category, ok := event.Details["category"].(string); ok {
+				categories = append(categories, category)
+			}
+		case "categorySkipped":
+			skipped++
+			if category, ok := event.Details["category"].(string); ok {
+				categories = append(categories, category)
+			}
+		case "roundEnded":
+			round := detailInt(event.Details["round"])
+			scores := detailScores(event.Details["scores"])
+			rounds = append(rounds, roundSummary{Round: round, Categories: categories, Scores: scores})
+			categories = nil
+		}
+	}
+	if r.round == totalRounds {
+		scores := make(map[string]int, len(r.roundScores))
+		for clientID, points := range r.roundScores {
+			scores[clientID] = points
+		}
+		rounds = append(rounds, roundSummary{Round: r.round, Categories: categories, Scores: scores})
+	}
+	for i := range rounds {
+		if rounds[i].Categories == nil {
+			rounds[i].Categories = []string{}
+		}
+		if rounds[i].Scores == nil {
+			rounds[i].Scores = map[string]int{}
+		}
+	}
+
+	return map[string]interface{}{
+		"type":              "gameSummary",
+		"rounds":            rounds,
+		"duration":          int(time.Since(started).Seconds()),
+		"categoriesSkipped": skipped,
+		"topScorer":         topScorer,
+	}
+}
+
+// winnerLocked returns the display name, or the client ID if it has none,
+// of the player with the highest total score. Ties go to the lowest client
+// ID so every instance picks the same winner. r.mu must be held.
+func (r *Room) winnerLocked() string {
+	clientIDs := make([]string, 0, len(r.scores))
+	for clientID := range r.scores {
+		clientIDs = append(clientIDs, clientID)
+	}
+	if len(clientIDs) == 0 {
+		return ""
+	}
+	sort.Slice(clientIDs, func(i, j int) bool {
+		a, b := clientIDs[i], clientIDs[j]
+		if r.scores[a] != r.scores[b] {
+			return r.scores[a] > r.scores[b]
+		}
+		return a < b
+	})
+
+	if name := r.clientNames[clientIDs[0]]; name != "" {
+		return name
+	}
+	return clientIDs[0]
+}
+
+// stopPostGameTimer cancels a pending removal of the room after its game
+// ended. It runs in room.run().
+func (r *Room) stopPostGameTimer() {
+	if r.postGameTimer != nil {
+		r.postGameTimer.Stop()
+		r.postGameTimer = nil
+	}
+}
+
+// handlePostGameTimeout removes the room once Config.PostGameTimeout passed
+// after its game ended. A timeout that fires after a reset is ignored. It
+// runs in room.run().
+func (r *Room) handlePostGameTimeout() {
+	r.mu.Lock()
+	ended := r.state == StateEnded
+	r.mu.Unlock()
+	if !ended || r.postGameTimer == nil {
+		return
+	}
+	r.postGameTimer = nil
+
+	slog.Info("Removing room after the game ended", slog.String("room", r.id))
+	r.server.removeRoom(r, "gameEnded")
+}
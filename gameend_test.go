@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRoundEndedDetails(t *testing.T) {
+	logged := RoomEvent{Type: "roundEnded", Details: map[string]interface{}{
+		"round":  3,
+		"scores": map[string]int{"a": 10, "b": 0},
+	}}
+	raw, err := json.Marshal(logged)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var restored RoomEvent
+	if err := json.Unmarshal(raw, &restored); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		event RoomEvent
+	}{
+		{"logged", logged},
+		{"restored from the store", restored},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detailInt(tt.event.Details["round"]); got != 3 {
+				t.Errorf("round = %d, want 3", got)
+			}
+			want := map[string]int{"a": 10, "b": 0}
+			if got := detailScores(tt.event.Details["scores"]); !reflect.DeepEqual(got, want) {
+				t.Errorf("scores = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDetailInt(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  int
+	}{
+		{7, 7},
+		{float64(7), 7},
+		{nil, 0},
+		{"7", 0},
+	}
+	for _, tt := range tests {
+		if got := detailInt(tt.value); got != tt.want {
+			t.Errorf("detailInt(%#v) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
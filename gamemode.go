@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrUnhandledMessage is returned by GameMode.HandleMessage for message types
+// the mode does not know. The room then relays the message to the peers.
+var ErrUnhandledMessage = errors.New("unhandled message type")
+
+// GameMode holds the game-specific rules of a room so alternative games can
+// reuse the room infrastructure (connections, broadcasts, reconnects).
+type GameMode interface {
+	// HandleMessage processes a message sent by conn. It is called from the
+	// connection's reader goroutine.
+	HandleMessage(room *Room, conn *websocket.Conn, msg map[string]interface{}) error
+	// OnClientJoin is called from room.run() after a player registered.
+	OnClientJoin(room *Room, conn *websocket.Conn)
+	// OnClientLeave is called from room.run() after a player unregistered.
+	OnClientLeave(room *Room, conn *websocket.Conn)
+}
+
+// DefaultGameMode is the category association game: players draw a
+// category, reveal their answers and score points over several rounds.
+type DefaultGameMode struct{}
+
+func (DefaultGameMode) HandleMessage(room *Room, conn *websocket.Conn, msg map[string]interface{}) error {
+	msgType, _ := msg["type"].(string)
+	switch MessageType(msgType) {
+	case TypeNewCategory:
+		room.mu.Lock()
+		usedCategories := room.usedCategories
+		categories := room.categories
+		room.mu.Unlock()
+		tags := parseTags(msg["tags"])
+		newCategory, reset, ok := getUniqueCategory(categories, usedCategories, tags)
+		if !ok {
+			return &CategoryExhaustedError{Tags: tags}
+		}
+		room.server.recordCategoryUsage(newCategory)
+		newCategoryMsg, err := json.Marshal(map[string]interface{}{
+			"type":  "newCategory",
+			"value": newCategory,
+		})
+		if err != nil {
+			return fmt.Errorf("encoding newCategory: %w", err)
+		}
+		enqueue(room, room.broadcast, BroadcastMessage{
+			message:         newCategoryMsg,
+			sender:          conn,
+			msgType:         TypeNewCategory,
+			includeSender:   true,
+			category:        newCategory,
+			resetCategories: reset,
+			timestamp:       time.Now(),
+		})
+		room.resetSkipVotes()
+		room.logEvent("categorySelected", map[string]interface{}{
+			"clientId": room.clientID(conn),
+			"category": newCategory,
+			"tags":     tags,
+		})
+	case TypeReveal:
+		// Counted in room.run() so simultaneous reveals cannot race
+		enqueue(room, room.broadcast, BroadcastMessage{
+			sender:  conn,
+			msgType: TypeReveal,
+			control: true,
+		})
+	case TypeResetGame:
+		// Applied in room.run() so it cannot interleave with a round ending
+		enqueue(room, room.broadcast, BroadcastMessage{
+			sender:  conn,
+			msgType: TypeResetGame,
+			control: true,
+		})
+	case TypeGameEnd:
+		enqueue(room, room.broadcast, BroadcastMessage{
+			sender:  conn,
+			msgType: TypeGameEnd,
+			control: true,
+		})
+	case TypeCancelCountdown:
+		enqueue(room, room.broadcast, BroadcastMessage{
+			sender:  conn,
+			msgType: TypeCancelCountdown,
+			control: true,
+		})
+	case TypeSkipCategory:
+		room.voteSkip(conn)
+	case TypeNewRound:
+		room.startRound(conn)
+	case TypeScore:
+		room.submitScore(conn, msg["points"])
+	default:
+		return ErrUnhandledMessage
+	}
+	return nil
+}
+
+// OnClientJoin starts the clock on the current round once the room is full.
+// When the room fills up for the first time, the clock only starts with
+// Config.AutoStartTimer; otherwise the first "newRound" starts it. With
+// Config.AutoStartCountdown it starts when the countdown ran out instead.
+func (DefaultGameMode) OnClientJoin(room *Room, conn *websocket.Conn) {
+	if len(room.clients) != room.maxClients {
+		return
+	}
+	config := room.server.config
+	room.mu.Lock()
+	if room.state == StatePlaying || (config.AutoStartTimer && config.AutoStartCountdown <= 0) {
+		room.startRoundTimerLocked()
+	}
+	room.mu.Unlock()
+}
+
+// OnClientLeave pauses the round clock until the room is full again.
+func (DefaultGameMode) OnClientLeave(room *Room, conn *websocket.Conn) {
+	room.mu.Lock()
+	room.stopRoundTimerLocked()
+	room.mu.Unlock()
+}
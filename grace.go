@@ -0,0 +1,104 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// handleDisconnect is called from room.run() when reading from a connection
+// failed. Players keep their slot for Config.DisconnectGrace so a brief
+// network hiccup does not count as leaving; everyone else is unregistered
+// right away.
+func (r *Room) handleDisconnect(conn *websocket.Conn) {
+	grace := r.server.config.DisconnectGrace
+	if _, ok := r.clients[conn]; !ok || grace <= 0 {
+		r.handleUnregister(conn)
+		return
+	}
+	if _, ok := r.graceTimers[conn]; ok {
+		return
+	}
+
+	slog.Info("Client disconnected, waiting for it to come back", slog.String("room", r.id), slog.String("client", r.clientIDs[conn]), slog.Duration("grace", grace))
+	r.graceTimers[conn] = time.AfterFunc(grace, func() {
+		select {
+		case r.unregister <- conn:
+		case <-r.ctx.Done():
+		}
+	})
+}
+
+// inGrace reports whether conn dropped and is waiting out its grace period.
+func (r *Room) inGrace(conn *websocket.Conn) bool {
+	_, ok := r.graceTimers[conn]
+	return ok
+}
+
+// stopGrace cancels the grace period of conn, if any.
+func (r *Room) stopGrace(conn *websocket.Conn) {
+	if timer, ok := r.graceTimers[conn]; ok {
+		timer.Stop()
+		delete(r.graceTimers, conn)
+	}
+}
+
+// resumeGraced hands the slot of a player still in its grace period over to
+// the connection it came back on. It reports false if the token does not
+// belong to such a player.
+func (r *Room) resumeGraced(req reconnectRequest) bool {
+	var old *websocket.Conn
+	for conn, token := range r.sessions {
+		if token == req.token && r.inGrace(conn) {
+			old = conn
+			break
+		}
+	}
+	if old == nil {
+		return false
+	}
+
+	r.stopGrace(old)
+	if waiter, ok := r.removeWaiter(req.conn); ok {
+		close(waiter.ready)
+	}
+	if _, registered := r.clients[req.conn]; registered {
+		// The new connection joined on its own first; it takes over the old
+		// slot instead of holding a second one
+		r.server.metrics.activePlayers.Add(-1)
+	}
+	delete(r.clients, old)
+	delete(r.sessions, old)
+	r.clients[req.conn] = true
+	r.sessions[req.conn] = req.token
+
+	r.mu.Lock()
+	clientID := r.clientIDs[old]
+	// A new connection that joined on its own first gives up the client ID
+	// it got for that
+	tempID := r.clientIDs[req.conn]
+	delete(r.clientIDs, old)
+	delete(r.pingSent, old)
+	delete(r.appPingSent, old)
+	delete(r.latencies, old)
+	r.clientIDs[req.conn] = clientID
+	if r.host == old {
+		r.host = req.conn
+	}
+	r.lastActivity = time.Now()
+	r.mu.Unlock()
+	old.Close()
+	r.forgetRequestID(old)
+	r.forgetFingerprint(old)
+
+	slog.Info("Client resumed within grace period", slog.String("room", r.id), slog.String("client", clientID), remoteAttr(req.conn))
+	r.logEvent("clientReconnected", map[string]interface{}{
+		"clientId": clientID,
+	})
+	if tempID != "" {
+		r.retireClientID(req.conn, tempID)
+	}
+	r.sendReconnected(req.conn, req.token, clientID)
+	return true
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+)
+
+// AttachableGameMode is implemented by game modes that react to room events
+// through the room's OnClientJoin, OnClientLeave and OnMessage hooks. Attach
+// is called once when the room is created, before room.run() starts, and is
+// where the mode sets the hooks.
+type AttachableGameMode interface {
+	GameMode
+	Attach(room *Room)
+}
+
+// runJoinHook calls OnClientJoin if it is set. It runs in room.run().
+func (r *Room) runJoinHook(clientID string) {
+	if r.OnClientJoin != nil {
+		r.OnClientJoin(clientID)
+	}
+}
+
+// runLeaveHook calls OnClientLeave if it is set. It runs in room.run().
+func (r *Room) runLeaveHook(clientID string) {
+	if r.OnClientLeave != nil {
+		r.OnClientLeave(clientID)
+	}
+}
+
+// runMessageHook passes a message a player sent to be relayed to OnMessage if
+// it is set. Messages the server builds, even on behalf of a player, and
+// binary frames are not passed on. It runs in room.run().
+func (r *Room) runMessageHook(broadcastMsg BroadcastMessage) {
+	if r.OnMessage == nil || broadcastMsg.includeSender || broadcastMsg.frameType == websocket.BinaryMessage {
+		return
+	}
+	clientID, ok := r.clientIDs[broadcastMsg.sender]
+	if !ok {
+		return
+	}
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal(broadcastMsg.message, &msg); err != nil {
+		r.connLogger(broadcastMsg.sender).Error("Error decoding message for OnMessage", slog.String("room", r.id), slog.String("client", clientID), slog.Any("error", err))
+		return
+	}
+	r.OnMessage(clientID, msg)
+}
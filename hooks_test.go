@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// hookMode records what OnMessage is called with.
+type hookMode struct {
+	DefaultGameMode
+	messages chan string
+}
+
+func (m hookMode) Attach(room *Room) {
+	room.OnMessage = func(clientID string, msg map[string]interface{}) {
+		msgType, _ := msg["type"].(string)
+		m.messages <- msgType
+	}
+}
+
+// OnMessage sees the messages players relay, not the ones the server builds
+// on their behalf.
+func TestMessageHookOnlyRelayed(t *testing.T) {
+	s, url := newTestServer(t, nil)
+	mode := hookMode{messages: make(chan string, 10)}
+	if _, _, err := s.getOrCreateRoom("hooks", RoomOptions{MaxClients: 2, Mode: mode}); err != nil {
+		t.Fatalf("getOrCreateRoom: %v", err)
+	}
+	sender := dial(t, url, "room=hooks")
+	expect(t, sender, "welcome")
+	peer := dial(t, url, "room=hooks")
+	expect(t, peer, "welcome")
+
+	send(t, sender, map[string]interface{}{"type": "setName", "name": "Anna"})
+	expect(t, peer, "nameSet")
+	send(t, sender, map[string]interface{}{"type": "playerInput", "value": "Hund"})
+	expect(t, peer, "playerInput")
+
+	select {
+	case msgType := <-mode.messages:
+		if msgType != "playerInput" {
+			t.Errorf("OnMessage got %q, want playerInput", msgType)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnMessage was not called")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+)
+
+// hostMessageTypes may only be sent by the room's host.
+var hostMessageTypes = map[MessageType]bool{
+	TypeNewCategory:     true,
+	TypeNewRound:        true,
+	TypeResetGame:       true,
+	TypeSetAllowedTypes: true,
+	TypeGameEnd:         true,
+	TypeCancelCountdown: true,
+}
+
+// isHost reports whether conn is the room's host.
+func (r *Room) isHost(conn *websocket.Conn) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.host == conn
+}
+
+// sendNotHost tells conn that only the host may send msgType.
+func (r *Room) sendNotHost(conn *websocket.Conn, msgType MessageType) {
+	if err := r.writeJSON(conn, map[string]interface{}{
+		"type":    "notHost",
+		"msgType": msgType,
+	}); err != nil {
+		slog.Error("Error sending notHost message", slog.String("room", r.id), remoteAttr(conn), slog.Any("error", err))
+	}
+}
+
+// transferHost lets the host hand over to another player, either the one
+// named by "clientId" or any other player if none is given.
+func (r *Room) transferHost(conn *websocket.Conn, msg map[string]interface{}) {
+	wanted, _ := msg["clientId"].(string)
+
+	r.mu.Lock()
+	if r.host != conn {
+		r.mu.Unlock()
+		r.sendNotHost(conn, "transferHost")
+		return
+	}
+	var target *websocket.Conn
+	for client, clientID := range r.clientIDs {
+		if client != conn && (wanted == "" || clientID == wanted) {
+			target = client
+			break
+		}
+	}
+	if target == nil {
+		r.mu.Unlock()
+		if err := r.writeJSON(conn, map[string]interface{}{
+			"type": "error",
+			"code": "unknownClient",
+		}); err != nil {
+			slog.Error("Error sending unknownClient message", slog.String("room", r.id), remoteAttr(conn), slog.Any("error", err))
+		}
+		return
+	}
+	r.host = target
+	hostID := r.clientIDs[target]
+	r.mu.Unlock()
+
+	slog.Info("Host transferred", slog.String("room", r.id), slog.String("host", hostID))
+	r.broadcastJSON(conn, map[string]interface{}{
+		"type":     "hostChanged",
+		"clientId": hostID,
+	})
+}
+
+// claimHostIfVacant makes conn the host if the room has none. It runs in
+// room.run().
+func (r *Room) claimHostIfVacant(conn *websocket.Conn) {
+	r.mu.Lock()
+	if r.host != nil {
+		r.mu.Unlock()
+		return
+	}
+	r.host = conn
+	hostID := r.clientIDs[conn]
+	r.mu.Unlock()
+	r.announceHost(hostID)
+}
+
+// handleHostLeft passes host status on to another connected player once the
+// host is gone. It runs in room.run().
+func (r *Room) handleHostLeft(conn *websocket.Conn) {
+	r.mu.Lock()
+	if r.host != conn {
+		r.mu.Unlock()
+		return
+	}
+	r.host = nil
+	var hostID string
+	for client, clientID := range r.clientIDs {
+		r.host = client
+		hostID = clientID
+		break
+	}
+	r.mu.Unlock()
+
+	if hostID != "" {
+		r.announceHost(hostID)
+	}
+}
+
+// announceHost tells every player who the host is. It runs in room.run().
+func (r *Room) announceHost(hostID string) {
+	slog.Info("New host", slog.String("room", r.id), slog.String("host", hostID))
+	broadcastMsg, err := newBroadcast(nil, map[string]interface{}{
+		"type":     "hostChanged",
+		"clientId": hostID,
+	})
+	if err != nil {
+		slog.Error("Error marshalling hostChanged message", slog.String("room", r.id), slog.Any("error", err))
+		return
+	}
+	r.broadcastMessage(broadcastMsg)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// minKickVotePlayers is the smallest room in which players may vote to kick
+// a peer. With two players a single vote would be a majority.
+const minKickVotePlayers = 3
+
+// voteKick records conn's vote to remove the player named by
+// "targetClientId". Once at least half of the players voted, the target is
+// kicked. Votes on a target expire Config.KickVoteTimeout after the first
+// one. It runs in the connection's reader goroutine.
+func (r *Room) voteKick(conn *websocket.Conn, msg map[string]interface{}) {
+	target, _ := msg["targetClientId"].(string)
+
+	r.mu.Lock()
+	voter := r.clientIDs[conn]
+	if voter == "" {
+		r.mu.Unlock()
+		return
+	}
+	if len(r.clientIDs) < minKickVotePlayers {
+		r.mu.Unlock()
+		r.sendKickVoteError(conn, "notEnoughPlayers")
+		return
+	}
+	var targetConn *websocket.Conn
+	for client, clientID := range r.clientIDs {
+		if clientID == target {
+			targetConn = client
+			break
+		}
+	}
+	if targetConn == nil || target == voter {
+		r.mu.Unlock()
+		r.sendKickVoteError(conn, "unknownClient")
+		return
+	}
+
+	if r.kickVotes[target] == nil {
+		r.kickVotes[target] = make(map[string]bool)
+		r.kickVoteTimers[target] = time.AfterFunc(r.server.config.KickVoteTimeout, func() {
+			r.expireKickVote(target)
+		})
+	}
+	r.kickVotes[target][voter] = true
+	votes := len(r.kickVotes[target])
+	required := int(math.Ceil(float64(len(r.clientIDs)) / 2))
+	if votes < required {
+		r.mu.Unlock()
+		r.broadcastJSON(conn, map[string]interface{}{
+			"type":           "kickVoted",
+			"targetClientId": target,
+			"votes":          votes,
+			"required":       required,
+		})
+		return
+	}
+	r.clearKickVoteLocked(target)
+	r.logEventLocked("kickVotePassed", map[string]interface{}{
+		"clientId": voter,
+		"target":   target,
+		"votes":    votes,
+	})
+	r.mu.Unlock()
+
+	slog.Info("Player kicked by vote", slog.String("room", r.id), slog.String("client", target), slog.Int("votes", votes))
+	if err := r.Kick(targetConn, "vote"); err != nil {
+		// The target left before the kick went through
+		return
+	}
+	r.broadcastJSON(conn, map[string]interface{}{
+		"type":           "kickVoteResult",
+		"targetClientId": target,
+		"result":         "kicked",
+	})
+}
+
+// expireKickVote drops the votes on target once Config.KickVoteTimeout
+// passed without a majority. It runs in the vote's timer goroutine.
+func (r *Room) expireKickVote(target string) {
+	r.mu.Lock()
+	if r.kickVotes[target] == nil || r.ctx.Err() != nil {
+		r.mu.Unlock()
+		return
+	}
+	r.clearKickVoteLocked(target)
+	r.mu.Unlock()
+
+	r.broadcastJSON(nil, map[string]interface{}{
+		"type":           "kickVoteResult",
+		"targetClientId": target,
+		"result":         "expired",
+	})
+}
+
+// clearKickVoteLocked forgets the votes on target. r.mu must be held.
+func (r *Room) clearKickVoteLocked(target string) {
+	if timer := r.kickVoteTimers[target]; timer != nil {
+		timer.Stop()
+	}
+	delete(r.kickVoteTimers, target)
+	delete(r.kickVotes, target)
+}
+
+// forgetKickVotesLocked drops the votes on a client that left and the votes
+// it cast, so they do not count towards a majority of the players still
+// there. r.mu must be held.
+func (r *Room) forgetKickVotesLocked(clientID string) {
+	r.clearKickVoteLocked(clientID)
+	for target, voters := range r.kickVotes {
+		delete(voters, clientID)
+		if len(voters) == 0 {
+			r.clearKickVoteLocked(target)
+		}
+	}
+}
+
+// sendKickVoteError tells conn why its kick vote was not counted.
+func (r *Room) sendKickVoteError(conn *websocket.Conn, code string) {
+	if err := r.writeJSON(conn, map[string]interface{}{
+		"type": "error",
+		"code": code,
+	}); err != nil {
+		slog.Error("Error sending kick vote error", slog.String("room", r.id), remoteAttr(conn), slog.String("code", code), slog.Any("error", err))
+	}
+}
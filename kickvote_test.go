@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+// A player that leaves takes its kick votes with it.
+func TestKickVoteForgetsLeaver(t *testing.T) {
+	s, url := newTestServer(t, func(config *Config) {
+		config.DisconnectGrace = 0
+		config.ReconnectWindow = 0
+	})
+	if _, _, err := s.getOrCreateRoom("kickvote", RoomOptions{MaxClients: 4}); err != nil {
+		t.Fatalf("getOrCreateRoom: %v", err)
+	}
+	leaves := dial(t, url, "room=kickvote")
+	expect(t, leaves, "welcome")
+	voter := dial(t, url, "room=kickvote")
+	expect(t, voter, "welcome")
+	third := dial(t, url, "room=kickvote")
+	expect(t, third, "welcome")
+	target := dial(t, url, "room=kickvote")
+	targetID := expect(t, target, "welcome")["clientId"]
+	expect(t, voter, "gameStart")
+
+	send(t, leaves, map[string]interface{}{"type": "kickVote", "targetClientId": targetID})
+	expect(t, voter, "kickVoted")
+	leaves.Close()
+	expect(t, voter, "clientLeft")
+
+	send(t, voter, map[string]interface{}{"type": "kickVote", "targetClientId": targetID})
+	msg := expect(t, voter, "kickVoted")
+	if msg["votes"] != float64(1) || msg["required"] != float64(2) {
+		t.Errorf("kickVoted votes = %v of %v, want 1 of 2", msg["votes"], msg["required"])
+	}
+}
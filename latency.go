@@ -0,0 +1,105 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// recordPong computes the round-trip time of the last heartbeat ping sent to
+// conn. It runs in the connection's reader goroutine as its pong handler.
+func (r *Room) recordPong(conn *websocket.Conn) {
+	r.mu.Lock()
+	sent, ok := r.pingSent[conn]
+	if !ok {
+		r.mu.Unlock()
+		return
+	}
+	delete(r.pingSent, conn)
+	rtt := time.Since(sent)
+	r.latencies[conn] = rtt
+	r.mu.Unlock()
+
+	r.server.metrics.rttCount.Add(1)
+	r.server.metrics.rttTotal.Add(int64(rtt))
+}
+
+// sendAppPing sends an application-level ping to every player, for proxies
+// that strip protocol pings. Players that left a ping unanswered for longer
+// than Config.PongTimeout are considered stale and unregistered. It runs in
+// room.run().
+func (r *Room) sendAppPing() {
+	now := time.Now()
+	timeout := r.server.config.PongTimeout
+	for client := range r.clients {
+		if client == nil || r.inGrace(client) {
+			continue
+		}
+
+		// appPingSent keeps the oldest unanswered ping
+		r.mu.Lock()
+		sent, pending := r.appPingSent[client]
+		if !pending {
+			r.appPingSent[client] = now
+		}
+		clientID := r.clientIDs[client]
+		r.mu.Unlock()
+		if pending && timeout > 0 && now.Sub(sent) > timeout {
+			r.connLogger(client).Warn("No pong received, unregistering stale client", slog.String("room", r.id), slog.String("client", clientID), slog.Duration("since", now.Sub(sent)))
+			r.handleUnregister(client)
+			continue
+		}
+
+		if err := r.writeJSON(client, map[string]interface{}{
+			"type":       "ping",
+			"serverTime": now.UnixMilli(),
+		}); err != nil {
+			r.handleUnregister(client)
+		}
+	}
+}
+
+// recordAppPong measures the round-trip time from the serverTime a client
+// echoes back in its "pong". It runs in the connection's reader goroutine.
+func (r *Room) recordAppPong(conn *websocket.Conn, msg map[string]interface{}) {
+	serverTime, _ := msg["serverTime"].(float64)
+	rtt := time.Since(time.UnixMilli(int64(serverTime)))
+	if rtt < 0 {
+		return
+	}
+
+	r.mu.Lock()
+	if _, ok := r.clientIDs[conn]; !ok {
+		r.mu.Unlock()
+		return
+	}
+	delete(r.appPingSent, conn)
+	r.latencies[conn] = rtt
+	r.mu.Unlock()
+
+	r.server.metrics.rttCount.Add(1)
+	r.server.metrics.rttTotal.Add(int64(rtt))
+}
+
+// latencyStatsLocked returns the average and 99th percentile of the clients' last
+// measured round-trip times in milliseconds. r.mu must be held.
+func (r *Room) latencyStatsLocked() (avg, p99 float64) {
+	if len(r.latencies) == 0 {
+		return 0, 0
+	}
+
+	values := make([]float64, 0, len(r.latencies))
+	var total float64
+	for _, rtt := range r.latencies {
+		ms := float64(rtt) / float64(time.Millisecond)
+		values = append(values, ms)
+		total += ms
+	}
+	sort.Float64s(values)
+
+	rank := int(math.Ceil(0.99*float64(len(values)))) - 1
+	return total / float64(len(values)), values[rank]
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// While a room is locked, new players wait in the join queue instead of
+// joining mid-transition. The room's own round transitions happen within a
+// single room.run() iteration and need no lock; it is for game modes whose
+// transitions span several messages, see LockJoins. Players reclaiming their
+// slot are not affected.
+
+// lockRoom stops new players from joining until unlockRoom is called or
+// Config.LockTimeout has passed. It runs in room.run().
+func (r *Room) lockRoom() {
+	r.locked = true
+	if r.lockTimer != nil {
+		r.lockTimer.Stop()
+	}
+	r.lockTimer = time.AfterFunc(r.server.config.LockTimeout, func() {
+		select {
+		case r.broadcast <- BroadcastMessage{msgType: TypeUnlockRoom, control: true}:
+		case <-r.ctx.Done():
+		}
+	})
+	slog.Debug("Room locked", slog.String("room", r.id))
+}
+
+// unlockRoom lets new players join again and admits the ones that queued up
+// while the room was locked. It runs in room.run().
+func (r *Room) unlockRoom() {
+	if r.lockTimer != nil {
+		r.lockTimer.Stop()
+		r.lockTimer = nil
+	}
+	if !r.locked {
+		return
+	}
+	r.locked = false
+	slog.Debug("Room unlocked", slog.String("room", r.id))
+	r.dequeue()
+}
+
+// LockJoins locks the room for a game mode's own transition. It must not be
+// called from room.run(), which calls lockRoom instead.
+func (r *Room) LockJoins() {
+	enqueue(r, r.broadcast, BroadcastMessage{msgType: TypeLockRoom, control: true})
+}
+
+// UnlockJoins ends a transition started with LockJoins. It must not be
+// called from room.run().
+func (r *Room) UnlockJoins() {
+	enqueue(r, r.broadcast, BroadcastMessage{msgType: TypeUnlockRoom, control: true})
+}
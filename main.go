@@ -4,18 +4,25 @@ import (
 	"context"
 	"embed"
 	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	"io/fs"
-	"log"
+	"log/slog"
 	"math/rand"
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 //go:embed client/dist
@@ -25,79 +32,299 @@ var dist embed.FS
 var data embed.FS
 
 const (
-	maxClients = 2
+	maxClients  = 2
+	defaultPack = "default"
+	// defaultHeartbeat is used when Config.HeartbeatInterval is not set
+	defaultHeartbeat = 25 * time.Second
 )
 
 type Config struct {
-	Port            string        `json:"port"`
-	MaxClients      int           `json:"maxClients"`
-	CleanupInterval time.Duration `json:"cleanupInterval"`
-	RoomTimeout     time.Duration `json:"roomTimeout"`
-	ReadTimeout     time.Duration `json:"readTimeout"`
-	WriteTimeout    time.Duration `json:"writeTimeout"`
+	Port                    string        `json:"port"`
+	MaxClients              int           `json:"maxClients"`
+	MaxSpectators           int           `json:"maxSpectators"`
+	CleanupInterval         time.Duration `json:"cleanupInterval"`
+	RoomTimeout             time.Duration `json:"roomTimeout"`
+	CleanupStrategy         string        `json:"cleanupStrategy"`
+	ReadTimeout             time.Duration `json:"readTimeout"`
+	WriteTimeout            time.Duration `json:"writeTimeout"`
+	ReconnectWindow         time.Duration `json:"reconnectWindow"`
+	MaxMessagesPerSecond    int           `json:"maxMessagesPerSecond"`
+	MaxMessageBytes         int64         `json:"maxMessageBytes"`
+	LogLevel                string        `json:"logLevel"`
+	HistorySize             int           `json:"historySize"`
+	MaxChatLength           int           `json:"maxChatLength"`
+	MaxRounds               int           `json:"maxRounds"`
+	MaxPointsPerRound       int           `json:"maxPointsPerRound"`
+	RoundDuration           time.Duration `json:"roundDuration"`
+	AutoStartTimer          bool          `json:"autoStartTimer"`
+	AutoStartCountdown      time.Duration `json:"autoStartCountdown"`
+	AdminToken              string        `json:"adminToken"`
+	TLSCertFile             string        `json:"tlsCertFile"`
+	TLSKeyFile              string        `json:"tlsKeyFile"`
+	RedirectPort            string        `json:"redirectPort"`
+	RedisAddr               string        `json:"redisAddr"`
+	AllowedOrigins          []string      `json:"allowedOrigins"`
+	EnableDeduplication     bool          `json:"enableDeduplication"`
+	RoomIDMinLength         int           `json:"roomIdMinLength"`
+	RoomIDMaxLength         int           `json:"roomIdMaxLength"`
+	ClientInactivityTimeout time.Duration `json:"clientInactivityTimeout"`
+	DisconnectGrace         time.Duration `json:"disconnectGrace"`
+	HeartbeatInterval       time.Duration `json:"heartbeatInterval"`
+	CoalesceWindow          time.Duration `json:"coalesceWindow"`
+	MaxQueueDepth           int           `json:"maxQueueDepth"`
+	RoomChannelBuffer       int           `json:"roomChannelBuffer"`
+	SlowSendThreshold       time.Duration `json:"slowSendThreshold"`
+	LockTimeout             time.Duration `json:"lockTimeout"`
+	KickVoteTimeout         time.Duration `json:"kickVoteTimeout"`
+	PostGameTimeout         time.Duration `json:"postGameTimeout"`
+	AppPingInterval         time.Duration `json:"appPingInterval"`
+	PongTimeout             time.Duration `json:"pongTimeout"`
+	MaxMessageAge           time.Duration `json:"maxMessageAge"`
+	EventLogSize            int           `json:"eventLogSize"`
+	TrackCategoryUsage      bool          `json:"trackCategoryUsage"`
+	DBPath                  string        `json:"dbPath"`
+	CategoriesFile          string        `json:"categoriesFile"`
+	WebhookURL              string        `json:"webhookUrl"`
+	WebhookSecret           string        `json:"webhookSecret"`
 }
 
 type Room struct {
-	clients        map[*websocket.Conn]bool
-	broadcast      chan BroadcastMessage
-	register       chan *websocket.Conn
-	unregister     chan *websocket.Conn
+	id            string
+	clients       map[*websocket.Conn]bool
+	spectators    map[*websocket.Conn]bool
+	broadcast     chan BroadcastMessage
+	priority      chan BroadcastMessage
+	register      chan joinRequest
+	spectate      chan *websocket.Conn
+	unregister    chan *websocket.Conn
+	disconnect    chan *websocket.Conn
+	reconnect     chan reconnectRequest
+	expire        chan string
+	roundTimeout  chan int
+	kick          chan kickRequest
+	snapshot      chan snapshotRequest
+	coalesced     []BroadcastMessage
+	coalesceTimer *time.Timer
+	coalesceFlush <-chan time.Time
+	countdown     *time.Ticker
+	countdownTick <-chan time.Time
+	countdownLeft int
+	closing       chan string
+	remote        <-chan []byte
+	clientIDs     map[*websocket.Conn]string
+	clientNames   map[string]string
+	sessions      map[*websocket.Conn]string
+	reserved      map[string]*reservation
+	graceTimers   map[*websocket.Conn]*time.Timer
+	writeMu       map[*websocket.Conn]*sync.Mutex
+	requestIDs    map[*websocket.Conn]string
+	fingerprints  map[*websocket.Conn]string
+	// connsMu guards writeMu, requestIDs and fingerprints
+	connsMu        sync.Mutex
+	waitingQueue   []joinRequest
+	dropped        []droppedClient
+	locked         bool
+	lockTimer      *time.Timer
+	postGameTimer  *time.Timer
+	allowedTypes   map[MessageType]bool
 	maxClients     int
+	heartbeat      time.Duration
+	pack           string
+	mode           GameMode
+	name           string
+	description    string
+	creatorID      string
+	host           *websocket.Conn
+	passwordHash   string
+	categories     []CategoryEntry
 	usedCategories []string
-	revealed       int
-	lastActivity   time.Time
-	done           chan struct{}
-	server         *Server
+	skipVotes      map[string]bool
+	kickVotes      map[string]map[string]bool
+	kickVoteTimers map[string]*time.Timer
+	typing         map[string]*typingState
+	history        []json.RawMessage
+	revealed       map[string]bool
+	round          int
+	state          RoomState
+	// paused is set while a game that lost a player waits for a refill
+	paused       bool
+	roundScores  map[string]int
+	scores       map[string]int
+	roundTimer   *time.Timer
+	pingSent     map[*websocket.Conn]time.Time
+	appPingSent  map[*websocket.Conn]time.Time
+	latencies    map[*websocket.Conn]time.Duration
+	emptyWaiters []chan struct{}
+	eventLog     []RoomEvent
+	eventStart   int
+	persistedAt  time.Time
+	createdAt    time.Time
+	lastActivity time.Time
+	server       *Server
+	// ctx is cancelled to stop the room; connection contexts derive from
+	// it. Cancelling is idempotent, unlike closing a channel.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// mu guards state shared with the reader goroutines: clientIDs,
+	// clientNames, reserved, spectators, lastActivity, name, description,
+	// creatorID, usedCategories, revealed, round, state, paused, roundScores,
+	// scores, roundTimer, skipVotes, kickVotes, kickVoteTimers, typing,
+	// pingSent, appPingSent, latencies and emptyWaiters.
+	// usedCategories, reserved and spectators are only modified by
+	// room.run(), which may read them without the lock.
+	mu sync.Mutex
+
+	// OnClientJoin, OnClientLeave and OnMessage let game modes react to room
+	// events, see AttachableGameMode. They are called from room.run() after
+	// a player registered, after a player unregistered and after a player's
+	// message was broadcast. Nil hooks are skipped.
+	OnClientJoin  func(clientID string)
+	OnClientLeave func(clientID string)
+	OnMessage     func(clientID string, msg map[string]interface{})
 }
 
 type BroadcastMessage struct {
 	message []byte
 	sender  *websocket.Conn
-	msgType string
+	msgType MessageType
+	// remote is set for messages relayed from another instance by the broker
+	remote bool
+	// ack is set when the sender asked to be told about delivery of seq
+	ack bool
+	seq int64
+	// includeSender delivers the message to its sender as well. It is set
+	// for messages the server generates on behalf of a client and unset for
+	// client messages relayed to the peers.
+	includeSender bool
+	// frameType is the websocket message type to deliver with; zero means
+	// websocket.TextMessage
+	frameType int
+	// control marks game actions that room.run() applies itself instead of
+	// delivering, e.g. "reveal"
+	control bool
+	// category is recorded as drawn when the message is delivered, so only
+	// room.run() modifies usedCategories; resetCategories starts the list
+	// over with it
+	category        string
+	resetCategories bool
+	// timestamp is when a reader goroutine queued the message; messages
+	// older than Config.MaxMessageAge are dropped instead of delivered
+	timestamp time.Time
 }
 
+// RoomInfo is a point-in-time summary of a room used by the room listing.
+type RoomInfo struct {
+	ID             string    `json:"id"`
+	Clients        int       `json:"clients"`
+	PlayerCount    int       `json:"playerCount"`
+	SpectatorCount int       `json:"spectatorCount"`
+	MaxClients     int       `json:"maxClients"`
+	CreatedAt      time.Time `json:"createdAt"`
+	CreatedAtUnix  int64     `json:"createdAtUnix"`
+	LastActivity   time.Time `json:"lastActivity"`
+	Round          int       `json:"round"`
+	State          string    `json:"state"`
+	UsedCategories int       `json:"usedCategories"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	Pack           string    `json:"pack"`
+	Host           string    `json:"host"`
+	AvgLatencyMs   float64   `json:"avgLatencyMs"`
+	P99LatencyMs   float64   `json:"p99LatencyMs"`
+}
+
+// Categories is the body of PUT /admin/categories.
 type Categories struct {
-	Categories []string `json:"categories"`
+	Categories []CategoryEntry `json:"categories"`
 }
 
 type Server struct {
-	rooms      map[string]*Room
-	mu         sync.Mutex
-	categories []string
-	distFS     fs.FS
-	config     Config
-	metrics    *Metrics
-	shutdown   chan struct{}
+	rooms         sync.Map // room ID -> *Room
+	categoryPacks map[string][]CategoryEntry
+	categoriesMu  sync.RWMutex
+	categoryUsage map[string]int64
+	usageMu       sync.RWMutex
+	distFS        fs.FS
+	config        Config
+	metrics       *Metrics
+	broker        Broker
+	store         *Store
+	upgrader      websocket.Upgrader
+	shutdown      chan struct{}
+	startedAt     time.Time
+	announcedAt   time.Time
+	announceMu    sync.Mutex
+	reports       []Report
+	reportsMu     sync.Mutex
+	// ctx is the parent of every room's context and is cancelled on
+	// shutdown; readers tracks the connection reader goroutines
+	ctx     context.Context
+	cancel  context.CancelFunc
+	readers sync.WaitGroup
 }
 
+// Metrics are updated on the hot path, so every counter is atomic. They are
+// exported to Prometheus through registry, see newMetrics.
 type Metrics struct {
-	activeRooms   int64
-	activeClients int64
-	messagesTotal int64
-	errorCount    int64
-	mu            sync.Mutex
-}
-
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins
-	},
+	registry         *prometheus.Registry
+	activeRooms      gauge
+	activePlayers    gauge
+	activeSpectators gauge
+	messagesTotal    counter
+	errorCount       counter
+	rttCount         atomic.Int64
+	// rttTotal is in nanoseconds
+	rttTotal atomic.Int64
+	// blockedSends counts sends to a room that waited longer than
+	// Config.SlowSendThreshold
+	blockedSends counter
+	// Totals and highs since the server started, see Server.Stats
+	roomsCreated     counter
+	clientsConnected counter
+	peakRooms        atomic.Int64
+	peakClients      atomic.Int64
+	errorsTotal      *prometheus.CounterVec
+	errorsByType     map[string]int64
+	errorsMu         sync.Mutex
 }
 
 func NewServer(config Config) *Server {
+	startedAt := time.Now()
 	server := &Server{
-		rooms:    make(map[string]*Room),
-		config:   config,
-		metrics:  &Metrics{},
-		shutdown: make(chan struct{}),
+		categoryUsage: make(map[string]int64),
+		config:        config,
+		metrics:       newMetrics(startedAt),
+		shutdown:      make(chan struct{}),
+		startedAt:     startedAt,
+	}
+	server.ctx, server.cancel = context.WithCancel(context.Background())
+	server.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     server.checkOrigin,
 	}
 	server.loadCategories()
 
+	if config.RedisAddr != "" {
+		server.broker = NewRedisBroker(config.RedisAddr)
+		slog.Info("Relaying room broadcasts through redis", slog.String("addr", config.RedisAddr))
+	}
+
+	if config.DBPath != "" {
+		store, err := OpenStore(config.DBPath)
+		if err != nil {
+			slog.Error("Error opening database", slog.String("path", config.DBPath), slog.Any("error", err))
+			os.Exit(1)
+		}
+		server.store = store
+		slog.Info("Persisting rooms", slog.String("path", config.DBPath))
+	}
+
 	distFS, err := fs.Sub(dist, "client/dist")
 	if err != nil {
-		log.Fatalf("Error creating sub-filesystem: %v", err)
+		slog.Error("Error creating sub-filesystem", slog.Any("error", err))
+		os.Exit(1)
 	}
 	server.distFS = distFS
 
@@ -105,137 +332,512 @@ func NewServer(config Config) *Server {
 }
 
 func (s *Server) loadCategories() {
-	data, err := data.ReadFile("data/categories.json")
+	packs, err := s.readCategories()
 	if err != nil {
-		log.Fatalf("Error reading categories file: %v", err)
+		slog.Error("Error loading categories", slog.Any("error", err))
+		os.Exit(1)
 	}
 
-	var categories Categories
-	err = json.Unmarshal(data, &categories)
-	if err != nil {
-		log.Fatalf("Error unmarshalling categories: %v", err)
-	}
+	s.categoryPacks = packs
+	slog.Info("Loaded categories", slog.Int("packs", len(packs)), slog.Int("count", len(packs[defaultPack])))
+}
 
-	s.categories = categories.Categories
-	log.Printf("Loaded %d categories", len(s.categories))
+// getCategoryPack returns the current category list of a pack. Lists are
+// replaced, never modified, so callers may keep using them without holding
+// the lock.
+func (s *Server) getCategoryPack(pack string) ([]CategoryEntry, bool) {
+	s.categoriesMu.RLock()
+	defer s.categoriesMu.RUnlock()
+	categories, ok := s.categoryPacks[pack]
+	return categories, ok
 }
 
-func (s *Server) getRandomCategory() string {
-	return s.categories[rand.Intn(len(s.categories))]
+// RoomOptions configures a room when it is created.
+type RoomOptions struct {
+	MaxClients   int
+	CategoryPack string
+	Password     string
+	Name         string
+	Description  string
+	// Mode defaults to DefaultGameMode
+	Mode GameMode
 }
 
-func NewRoom() *Room {
-	return &Room{
+// getOrCreateRoom returns the room with the given ID, creating it with the
+// given options if it does not exist yet. The options of an existing room are
+// never changed. The returned bool reports whether the room was created.
+func (s *Server) getOrCreateRoom(roomID string, opts RoomOptions) (*Room, bool, error) {
+	if opts.CategoryPack == "" {
+		opts.CategoryPack = defaultPack
+	}
+	if opts.Mode == nil {
+		opts.Mode = DefaultGameMode{}
+	}
+	categories, ok := s.getCategoryPack(opts.CategoryPack)
+	if !ok {
+		return nil, false, &UnknownPackError{Pack: opts.CategoryPack}
+	}
+
+	if room, ok := s.GetRoom(roomID); ok {
+		return room, false, nil
+	}
+
+	// Only hash for rooms that are about to be created, it is slow on purpose
+	var passwordHash string
+	if opts.Password != "" {
+		hash, err := hashPassword(opts.Password)
+		if err != nil {
+			return nil, false, err
+		}
+		passwordHash = hash
+	}
+
+	// The interval is fixed when the room is created, so config changes only
+	// apply to new rooms
+	heartbeat := s.config.HeartbeatInterval
+	if heartbeat <= 0 {
+		heartbeat = defaultHeartbeat
+	}
+
+	room := &Room{
+		id:             roomID,
 		clients:        make(map[*websocket.Conn]bool),
-		broadcast:      make(chan BroadcastMessage),
-		register:       make(chan *websocket.Conn),
-		unregister:     make(chan *websocket.Conn),
-		maxClients:     maxClients,
+		spectators:     make(map[*websocket.Conn]bool),
+		broadcast:      make(chan BroadcastMessage, s.config.RoomChannelBuffer),
+		priority:       make(chan BroadcastMessage, s.config.RoomChannelBuffer),
+		register:       make(chan joinRequest, s.config.RoomChannelBuffer),
+		spectate:       make(chan *websocket.Conn),
+		unregister:     make(chan *websocket.Conn, s.config.RoomChannelBuffer),
+		disconnect:     make(chan *websocket.Conn),
+		reconnect:      make(chan reconnectRequest),
+		expire:         make(chan string),
+		roundTimeout:   make(chan int),
+		kick:           make(chan kickRequest),
+		snapshot:       make(chan snapshotRequest),
+		closing:        make(chan string, 1),
+		clientIDs:      make(map[*websocket.Conn]string),
+		clientNames:    make(map[string]string),
+		sessions:       make(map[*websocket.Conn]string),
+		reserved:       make(map[string]*reservation),
+		graceTimers:    make(map[*websocket.Conn]*time.Timer),
+		writeMu:        make(map[*websocket.Conn]*sync.Mutex),
+		requestIDs:     make(map[*websocket.Conn]string),
+		fingerprints:   make(map[*websocket.Conn]string),
+		maxClients:     s.clampMaxClients(opts.MaxClients),
+		heartbeat:      heartbeat,
+		pack:           opts.CategoryPack,
+		mode:           opts.Mode,
+		name:           opts.Name,
+		description:    opts.Description,
+		passwordHash:   passwordHash,
+		categories:     categories,
 		usedCategories: make([]string, 0),
-		revealed:       0,
+		skipVotes:      make(map[string]bool),
+		kickVotes:      make(map[string]map[string]bool),
+		kickVoteTimers: make(map[string]*time.Timer),
+		typing:         make(map[string]*typingState),
+		revealed:       make(map[string]bool),
+		round:          1,
+		roundScores:    make(map[string]int),
+		scores:         make(map[string]int),
+		pingSent:       make(map[*websocket.Conn]time.Time),
+		appPingSent:    make(map[*websocket.Conn]time.Time),
+		latencies:      make(map[*websocket.Conn]time.Duration),
+		createdAt:      time.Now(),
 		lastActivity:   time.Now(),
-		done:           make(chan struct{}),
+		server:         s,
+	}
+	room.ctx, room.cancel = context.WithCancel(s.ctx)
+
+	// Another request may have created the room in the meantime; its room
+	// wins and this one is discarded
+	if existing, loaded := s.rooms.LoadOrStore(roomID, room); loaded {
+		room.cancel()
+		return existing.(*Room), false, nil
+	}
+	if s.broker != nil {
+		room.remote = s.broker.Subscribe(roomID)
 	}
+	s.metrics.activeRooms.Add(1)
+	s.metrics.roomsCreated.Add(1)
+	s.metrics.updatePeaks()
+	if mode, ok := room.mode.(AttachableGameMode); ok {
+		mode.Attach(room)
+	}
+	s.notifyWebhook("roomCreated", roomID)
+	go room.run()
+	return room, true, nil
+}
+
+// GetRoom looks up an active room. Together with RoomExists it is the way
+// for code outside the server, such as game modes or admin handlers, to get
+// at a room.
+func (s *Server) GetRoom(id string) (*Room, bool) {
+	value, ok := s.rooms.Load(id)
+	if !ok {
+		return nil, false
+	}
+	return value.(*Room), true
+}
+
+// RoomExists reports whether a room with the given ID is active.
+func (s *Server) RoomExists(id string) bool {
+	_, ok := s.rooms.Load(id)
+	return ok
+}
+
+// ListRooms returns a summary of all active rooms, oldest first.
+func (s *Server) ListRooms() []RoomInfo {
+	rooms := s.roomInfos()
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].CreatedAt.Before(rooms[j].CreatedAt)
+	})
+	return rooms
+}
+
+// roomInfos snapshots every active room. The room list is copied first so
+// no room is inspected while ranging over the map.
+func (s *Server) roomInfos() []RoomInfo {
+	var rooms []*Room
+	s.rooms.Range(func(_, value any) bool {
+		rooms = append(rooms, value.(*Room))
+		return true
+	})
+
+	infos := make([]RoomInfo, 0, len(rooms))
+	for _, room := range rooms {
+		infos = append(infos, room.Snapshot())
+	}
+	return infos
+}
+
+// Size returns the number of players in the room. Unlike len(r.clients),
+// which only room.run() may use, it is safe to call from any goroutine:
+// clientIDs has an entry for every player and is guarded by r.mu.
+func (r *Room) Size() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.clientIDs)
+}
+
+// slots returns the number of players and of slots held for reconnecting
+// clients. It is safe to call from any goroutine.
+func (r *Room) slots() (players, reserved int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.clientIDs), len(r.reserved)
+}
+
+// IsFull reports whether every player slot is taken. Slots reserved for
+// reconnects are not counted.
+func (r *Room) IsFull() bool {
+	return r.Size() >= r.maxClients
 }
 
-func (s *Server) getOrCreateRoom(roomID string) (*Room, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// IsEmpty reports whether the room has no players.
+func (r *Room) IsEmpty() bool {
+	return r.Size() == 0
+}
+
+// awaitingFirstPlayer reports whether no player has joined the room yet,
+// e.g. because it was created through POST /rooms.
+func (r *Room) awaitingFirstPlayer() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.creatorID == ""
+}
 
-	room, ok := s.rooms[roomID]
+// WaitForRoomEmpty returns a channel that is closed once the room has no
+// players left or is closed. It is closed right away if the room does not
+// exist or is already empty.
+func (s *Server) WaitForRoomEmpty(roomID string) <-chan struct{} {
+	done := make(chan struct{})
+	room, ok := s.GetRoom(roomID)
 	if !ok {
-		room = &Room{
-			clients:        make(map[*websocket.Conn]bool),
-			broadcast:      make(chan BroadcastMessage),
-			register:       make(chan *websocket.Conn),
-			unregister:     make(chan *websocket.Conn),
-			maxClients:     s.config.MaxClients,
-			usedCategories: make([]string, 0),
-			revealed:       0,
-			lastActivity:   time.Now(),
-			done:           make(chan struct{}),
-			server:         s,
-		}
-		s.rooms[roomID] = room
-		s.metrics.mu.Lock()
-		s.metrics.activeRooms++
-		s.metrics.mu.Unlock()
-		go room.run()
-	}
-	return room, nil
-}
-
-func (s *Server) handleWebSocket(conn *websocket.Conn, room *Room) {
+		close(done)
+		return done
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	if len(room.clientIDs) == 0 || room.ctx.Err() != nil {
+		close(done)
+		return done
+	}
+	room.emptyWaiters = append(room.emptyWaiters, done)
+	return done
+}
+
+// notifyEmptyLocked releases the WaitForRoomEmpty callers if the room has no
+// players left, or unconditionally if it is closing. r.mu must be held.
+func (r *Room) notifyEmptyLocked() {
+	if len(r.clientIDs) > 0 && r.ctx.Err() == nil {
+		return
+	}
+	for _, done := range r.emptyWaiters {
+		close(done)
+	}
+	r.emptyWaiters = nil
+}
+
+// LastActivity returns when a player last joined, left or came back. It is
+// safe to call from any goroutine.
+func (r *Room) LastActivity() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastActivity
+}
+
+// Snapshot returns a point-in-time summary of the room. HTTP handlers report
+// rooms through it rather than reading Room fields.
+func (r *Room) Snapshot() RoomInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	avgLatency, p99Latency := r.latencyStatsLocked()
+	return RoomInfo{
+		ID:             r.id,
+		Clients:        len(r.clientIDs),
+		PlayerCount:    len(r.clientIDs),
+		SpectatorCount: len(r.spectators),
+		MaxClients:     r.maxClients,
+		CreatedAt:      r.createdAt,
+		CreatedAtUnix:  r.createdAt.Unix(),
+		LastActivity:   r.lastActivity,
+		Round:          r.round,
+		State:          r.state.String(),
+		UsedCategories: len(r.usedCategories),
+		Name:           r.name,
+		Description:    r.description,
+		Pack:           r.pack,
+		Host:           r.clientIDs[r.host],
+		AvgLatencyMs:   avgLatency,
+		P99LatencyMs:   p99Latency,
+	}
+}
+
+func (s *Server) handleWebSocket(ctx context.Context, logger *slog.Logger, conn *websocket.Conn, room *Room, spectator bool, token string, first *frame) {
+	s.readers.Add(1)
+	defer s.readers.Done()
 	defer conn.Close()
+	room.addWriter(conn)
+	defer room.removeWriter(conn)
 
-	// Register the connection to the room
-	room.register <- conn
+	// Cancelling the context interrupts a pending read without closing the
+	// connection; the loop below then returns
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	// Pong handlers run inside ReadMessage, so install it before the reader
+	// loop starts rather than from room.run()
+	conn.SetPongHandler(func(string) error {
+		room.recordPong(conn)
+		return nil
+	})
+
+	// Register the connection to the room, reclaiming a reserved slot if the
+	// client came back with a session token. ready stays open while the
+	// connection waits in the join queue.
+	var ready chan struct{}
+	if spectator {
+		enqueue(room, room.spectate, conn)
+	} else if token != "" {
+		enqueue(room, room.reconnect, reconnectRequest{conn: conn, token: token})
+	} else {
+		ready = make(chan struct{})
+		enqueue(room, room.register, joinRequest{conn: conn, ready: ready})
+	}
+
+	limiter := newRateLimiter(s.config.MaxMessagesPerSecond)
+
+	// Players that go quiet for too long give up their slot
+	var inactivity *time.Timer
+	if !spectator && s.config.ClientInactivityTimeout > 0 {
+		inactivity = time.AfterFunc(s.config.ClientInactivityTimeout, func() {
+			room.handleInactivity(conn)
+		})
+		defer inactivity.Stop()
+	}
 
 	for {
-		_, message, err := conn.ReadMessage()
+		var (
+			frameType int
+			message   []byte
+			err       error
+		)
+		if first != nil {
+			// Read by handleConnections before registering, but not meant
+			// for it
+			frameType, message = first.frameType, first.data
+			first = nil
+		} else {
+			frameType, message, err = conn.ReadMessage()
+		}
+		if ctx.Err() != nil {
+			logger.Info("Connection cancelled", slog.String("room", room.id), remoteAttr(conn))
+			break
+		}
+		if err == nil {
+			s.metrics.messagesTotal.Add(1)
+		}
+		if err == nil && inactivity != nil {
+			inactivity.Reset(s.config.ClientInactivityTimeout)
+		}
 		if err != nil {
-			log.Printf("Error reading message: %v", err)
-			room.unregister <- conn
+			// gorilla closes the connection with 1009 when the read limit is hit
+			if errors.Is(err, websocket.ErrReadLimit) || websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+				s.metrics.countError("messageTooBig")
+				logger.Error("Message exceeded size limit", slog.String("room", room.id), remoteAttr(conn), slog.Int64("limit", s.config.MaxMessageBytes))
+			} else {
+				logger.Info("Error reading message", slog.String("room", room.id), remoteAttr(conn), slog.Any("error", err))
+			}
+			enqueue(room, room.disconnect, conn)
 			break
 		}
 
-		var msg map[string]interface{}
-		if err := json.Unmarshal(message, &msg); err != nil {
-			log.Printf("Error unmarshalling message: %v", err)
+		if !limiter.Allow() {
+			rateLimitedMsg, _ := json.Marshal(map[string]interface{}{
+				"type":       "rateLimited",
+				"retryAfter": limiter.RetryAfter(),
+			})
+			if err := room.writeMessage(conn, websocket.TextMessage, rateLimitedMsg); err != nil {
+				logger.Error("Error sending rateLimited message", slog.String("room", room.id), remoteAttr(conn), slog.Any("error", err))
+			}
 			continue
 		}
 
-		switch msg["type"] {
-		case "newCategory":
-			newCategory := s.getUniqueCategory(room.usedCategories)
-			newCategoryMsg, err := json.Marshal(map[string]interface{}{
-				"type":  "newCategory",
-				"value": newCategory,
+		// Spectators only watch; drop anything they try to send
+		if spectator {
+			notAllowedMsg, _ := json.Marshal(map[string]interface{}{
+				"type": "notAllowed",
 			})
-			if err != nil {
-				log.Printf("Error marshalling new category message: %v", err)
-				continue
+			if err := room.writeMessage(conn, websocket.TextMessage, notAllowedMsg); err != nil {
+				logger.Error("Error sending notAllowed message", slog.String("room", room.id), remoteAttr(conn), slog.Any("error", err))
 			}
-			room.broadcast <- BroadcastMessage{
-				message: newCategoryMsg,
-				sender:  conn,
-				msgType: "newCategory",
-			}
-			room.usedCategories = append(room.usedCategories, newCategory)
-		case "reveal":
-			room.revealed++
-			if room.revealed == len(room.clients) {
-				allRevealedMsg, err := json.Marshal(map[string]interface{}{
-					"type": "allRevealed",
-				})
-				if err != nil {
-					log.Printf("Error marshalling allRevealed message: %v", err)
+			continue
+		}
+
+		// Binary frames carry game payloads only, so queued connections
+		// cannot send them
+		if frameType == websocket.BinaryMessage {
+			if ready != nil {
+				select {
+				case <-ready:
+					ready = nil
+				default:
 					continue
 				}
-				room.broadcast <- BroadcastMessage{
-					message: allRevealedMsg,
-					sender:  conn,
-					msgType: "allRevealed",
+			}
+			room.handleBinaryMessage(conn, message)
+			continue
+		}
+
+		var msg map[string]interface{}
+		if err := json.Unmarshal(message, &msg); err != nil {
+			logger.Warn("Error unmarshalling message", slog.String("room", room.id), remoteAttr(conn), slog.Any("error", err))
+			continue
+		}
+
+		// Queued connections may only reclaim a reserved slot
+		if ready != nil {
+			select {
+			case <-ready:
+				ready = nil
+			default:
+				if msg["type"] != "reconnect" {
+					continue
 				}
-				room.revealed = 0
 			}
+		}
+
+		if err := validateMessage(msg); err != nil {
+			room.rejectMessage(conn, err)
+			continue
+		}
+
+		msgType := MessageType(msg["type"].(string))
+		if !ValidMessageTypes[msgType] {
+			room.sendUnknownMessageType(conn, msgType)
+			continue
+		}
+		if hostMessageTypes[msgType] && !room.isHost(conn) {
+			room.sendNotHost(conn, msgType)
+			continue
+		}
+		if required, ok := stateMessageTypes[msgType]; ok {
+			if current := room.State(); current != required {
+				room.sendInvalidState(conn, current)
+				continue
+			}
+		}
+
+		switch msgType {
+		case TypeReconnect:
+			token, _ := msg["token"].(string)
+			enqueue(room, room.reconnect, reconnectRequest{conn: conn, token: token})
+		case TypeSetRoomMeta:
+			room.setRoomMeta(conn, msg)
+		case TypeTransferHost:
+			room.transferHost(conn, msg)
+		case TypeSetName:
+			room.setName(conn, msg)
+		case TypeChat:
+			room.sendChat(conn, msg)
+		case TypeRoomInfo:
+			room.sendRoomInfo(conn)
+		case TypePong:
+			room.recordAppPong(conn, msg)
+		case TypeSetAllowedTypes:
+			room.requestAllowedTypes(conn, msg)
+		case TypeKickVote:
+			room.voteKick(conn, msg)
+		case TypeReport:
+			room.submitReport(conn, msg)
+		case TypeTyping:
+			room.setTyping(conn, msg)
 		default:
-			room.broadcast <- BroadcastMessage{message: message, sender: conn, msgType: msg["type"].(string)}
+			err := room.mode.HandleMessage(room, conn, msg)
+			if errors.Is(err, ErrUnhandledMessage) {
+				seq, ack := parseSeq(msg["seq"])
+				enqueue(room, room.broadcast, BroadcastMessage{message: message, sender: conn, msgType: msgType, includeSender: false, ack: ack, seq: seq, timestamp: time.Now()})
+			} else if err != nil {
+				room.handleMessageError(conn, msgType, err)
+			}
 		}
 	}
 }
 
-func (s *Server) getUniqueCategory(usedCategories []string) string {
-	if len(usedCategories) == len(s.categories) {
-		usedCategories = make([]string, 0)
+// getUniqueCategory draws a category that has not been used in the room yet.
+// With tags, only categories carrying all of them are considered and false
+// is returned once that subset is exhausted. Without tags the draw starts
+// over when every category has been used, and reset tells the caller to
+// clear the room's used list.
+func getUniqueCategory(categories []CategoryEntry, usedCategories []string, tags []string) (category string, reset, ok bool) {
+	used := make(map[string]bool, len(usedCategories))
+	for _, name := range usedCategories {
+		used[name] = true
 	}
 
-	for {
-		newCategory := s.getRandomCategory()
-		if !contains(usedCategories, newCategory) {
-			return newCategory
+	var candidates, unused []string
+	for _, category := range categories {
+		if !category.hasTags(tags) {
+			continue
+		}
+		candidates = append(candidates, category.Name)
+		if !used[category.Name] {
+			unused = append(unused, category.Name)
 		}
 	}
+
+	if len(unused) == 0 {
+		if len(tags) > 0 || len(candidates) == 0 {
+			return "", false, false
+		}
+		unused, reset = candidates, true
+	}
+	return unused[rand.Intn(len(unused))], reset, true
 }
 
 // Helper function to check if a slice contains a string
@@ -249,110 +851,808 @@ func contains(slice []string, item string) bool {
 }
 
 func (s *Server) handleConnections(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		s.metrics.mu.Lock()
-		s.metrics.errorCount++
-		s.metrics.mu.Unlock()
-		log.Printf("Error upgrading connection: %v", err)
+		s.metrics.countError("upgrade")
+		slog.Error("Error upgrading connection", slog.String("remote", r.RemoteAddr), slog.Any("error", err))
 		return
 	}
+	conn.SetReadLimit(s.config.MaxMessageBytes)
+	s.metrics.clientsConnected.Add(1)
+	requestID := newRequestID()
+	logger := slog.With(slog.String("requestId", requestID))
 
 	roomID := r.URL.Query().Get("room")
-	if roomID == "" {
-		log.Println("Error: Room ID is required")
+	if err := s.validateRoomID(roomID); err != nil {
+		logger.Warn("Invalid room ID", slog.String("room", roomID), remoteAttr(conn), slog.Any("error", err))
+		conn.WriteControl(
+			websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error()),
+			time.Now().Add(time.Second),
+		)
 		conn.Close()
 		return
 	}
 
-	room, err := s.getOrCreateRoom(roomID)
+	room, _, err := s.getOrCreateRoom(roomID, RoomOptions{
+		MaxClients:   parseMaxClients(r.URL.Query().Get("maxClients")),
+		CategoryPack: r.URL.Query().Get("pack"),
+	})
 	if err != nil {
-		s.metrics.mu.Lock()
-		s.metrics.errorCount++
-		s.metrics.mu.Unlock()
-		log.Printf("Error getting or creating room: %v", err)
-		conn.Close()
+		s.metrics.countError(errorCode(err))
+		logger.Error("Error getting or creating room", slog.String("room", roomID), slog.Any("error", err))
+		rejectConnection(conn, err)
 		return
 	}
 
-	// Check if the room is full before registering
-	if len(room.clients) >= s.config.MaxClients {
-		s.metrics.mu.Lock()
-		s.metrics.errorCount++
-		s.metrics.mu.Unlock()
-		log.Printf("Room %s is full. Connection rejected.", roomID)
-		conn.Close()
+	spectator := r.URL.Query().Get("spectator") == "true"
+	// The spectator limit is enforced by room.run(), which owns the
+	// spectators
+	if spectator {
+		if room.passwordHash != "" {
+			first, err := readFirstFrame(conn, s.config.ReadTimeout)
+			if err != nil {
+				logger.Info("Error reading auth message", slog.String("room", roomID), remoteAttr(conn), slog.Any("error", err))
+				conn.Close()
+				return
+			}
+			if !room.authenticate(conn, first.object()) {
+				conn.Close()
+				return
+			}
+		}
+		room.setRequestID(conn, requestID)
+		logger.Info("New spectator connected", slog.String("room", roomID), remoteAttr(conn))
+		ctx, cancel := context.WithCancel(room.ctx)
+		defer cancel()
+		s.handleWebSocket(ctx, logger, conn, room, true, "", nil)
+		return
+	}
+
+	// Slots held for disconnected clients can only be claimed back with
+	// their session token, and password protected rooms admit nobody else
+	// without the password. In both cases the client's first message
+	// decides, so it is read once here and dispatched on its type. Any
+	// other message is handled as the client's first regular one.
+	players, reserved := room.slots()
+	full := players+reserved >= room.maxClients
+	var (
+		token string
+		first *frame
+	)
+	if room.passwordHash != "" || full && reserved > 0 {
+		if first, err = readFirstFrame(conn, s.config.ReadTimeout); err != nil {
+			logger.Info("Error reading first message", slog.String("room", roomID), remoteAttr(conn), slog.Any("error", err))
+			conn.Close()
+			return
+		}
+		msg := first.object()
+		if t, _ := msg["token"].(string); msg["type"] == "reconnect" && t != "" {
+			// Clients reclaiming a slot already authenticated with their
+			// first connection
+			token, first = t, nil
+		} else if room.passwordHash != "" {
+			if !room.authenticate(conn, msg) {
+				conn.Close()
+				return
+			}
+			first = nil
+		}
+	}
+
+	// Everyone else waits in the join queue if it is enabled
+	if full && token == "" && s.config.MaxQueueDepth <= 0 {
+		s.metrics.countError("roomFull")
+		logger.Warn("Room is full, connection rejected", slog.String("room", roomID), remoteAttr(conn))
+		rejectConnection(conn, &RoomFullError{RoomID: roomID, MaxClients: room.maxClients})
+		return
+	}
+
+	room.setRequestID(conn, requestID)
+	if s.config.EnableDeduplication {
+		room.setFingerprint(conn, connFingerprint(r))
+	}
+	logger.Info("New client connected", slog.String("room", roomID), remoteAttr(conn))
+	ctx, cancel := context.WithCancel(room.ctx)
+	defer cancel()
+	s.handleWebSocket(ctx, logger, conn, room, false, token, first)
+}
+
+// validateRoomID checks that a client supplied room ID has an allowed length
+// and only contains letters, digits, hyphens and underscores.
+func (s *Server) validateRoomID(roomID string) error {
+	if roomID == "" {
+		return errors.New("room ID is required")
+	}
+	if len(roomID) < s.config.RoomIDMinLength || len(roomID) > s.config.RoomIDMaxLength {
+		return fmt.Errorf("room ID must be between %d and %d characters", s.config.RoomIDMinLength, s.config.RoomIDMaxLength)
+	}
+	for _, c := range roomID {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return errors.New("room ID may only contain letters, digits, hyphens and underscores")
+		}
+	}
+	return nil
+}
+
+// parseMaxClients converts the maxClients query parameter into a room
+// capacity. Missing or invalid values yield zero, meaning the default.
+func parseMaxClients(value string) int {
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		slog.Warn("Ignoring invalid maxClients value", slog.String("value", value))
+		return 0
+	}
+	return n
+}
+
+// clampMaxClients returns the capacity for a new room. Zero or negative values
+// fall back to the default capacity and the result is capped at
+// Config.MaxClients.
+func (s *Server) clampMaxClients(n int) int {
+	if n < 1 {
+		n = maxClients
+	}
+	if n > s.config.MaxClients {
+		n = s.config.MaxClients
+	}
+	return n
+}
+
+// parseLogLevel maps Config.LogLevel to a slog level, defaulting to info.
+func parseLogLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// remoteAttr returns the client's remote address as a log attribute.
+func remoteAttr(conn *websocket.Conn) slog.Attr {
+	return slog.String("remote", conn.RemoteAddr().String())
+}
+
+// enqueue sends v to room.run() on ch. Sends that had to wait longer than
+// Config.SlowSendThreshold are counted, so operators can tell when
+// Config.RoomChannelBuffer is too small. The value is dropped if the room
+// closes, since nothing receives from its channels anymore.
+func enqueue[T any](r *Room, ch chan<- T, v T) {
+	select {
+	case ch <- v:
 		return
+	default:
 	}
 
-	log.Printf("New client connected to room: %s", roomID)
-	s.handleWebSocket(conn, room)
+	start := time.Now()
+	select {
+	case ch <- v:
+	case <-r.ctx.Done():
+		return
+	}
+	if time.Since(start) > r.server.config.SlowSendThreshold {
+		r.server.metrics.blockedSends.Add(1)
+	}
 }
 
 func (r *Room) run() {
-	ticker := time.NewTicker(30 * time.Second) // Heartbeat ticker
+	ticker := time.NewTicker(r.heartbeat) // Heartbeat ticker
 	defer ticker.Stop()
 
+	// Application-level pings are off unless Config.AppPingInterval is set
+	var appPing <-chan time.Time
+	if interval := r.server.config.AppPingInterval; interval > 0 {
+		appPingTicker := time.NewTicker(interval)
+		defer appPingTicker.Stop()
+		appPing = appPingTicker.C
+	}
+
 	for {
+		// System work goes ahead of everything else that is pending, as
+		// select picks among ready cases at random
 		select {
-		case <-r.done:
+		case reason := <-r.closing:
+			r.handleClose(reason)
 			return
-		case client := <-r.register:
-			r.handleRegister(client)
+		case req := <-r.kick:
+			r.handleKick(req)
+			continue
+		case broadcastMsg := <-r.priority:
+			r.handleBroadcast(broadcastMsg)
+			continue
+		case <-ticker.C:
+			r.sendHeartbeat()
+			continue
+		default:
+		}
+
+		select {
+		case <-r.ctx.Done():
+			// The context is the only shutdown signal. The channels stay
+			// open because reader goroutines may still be sending on them;
+			// drain absorbs those sends until the room has gone quiet.
+			r.stopTimers()
+			r.drain()
+			return
+		case req := <-r.register:
+			r.handleRegister(req)
+		case spectator := <-r.spectate:
+			r.handleSpectate(spectator)
+		case req := <-r.reconnect:
+			r.handleReconnect(req)
+		case token := <-r.expire:
+			r.releaseSlot(token)
+		case round := <-r.roundTimeout:
+			r.handleRoundTimeout(round)
+		case req := <-r.kick:
+			r.handleKick(req)
+		case req := <-r.snapshot:
+			r.handleSnapshot(req)
+		case reason := <-r.closing:
+			r.handleClose(reason)
+			return
+		case message, ok := <-r.remote:
+			if !ok {
+				r.remote = nil
+				continue
+			}
+			r.handleRemoteMessage(message)
 		case client := <-r.unregister:
 			r.handleUnregister(client)
+		case client := <-r.disconnect:
+			r.handleDisconnect(client)
+		case broadcastMsg := <-r.priority:
+			r.handleBroadcast(broadcastMsg)
 		case broadcastMsg := <-r.broadcast:
-			r.broadcastMessage(broadcastMsg)
+			r.handleBroadcast(broadcastMsg)
+		case <-r.coalesceFlush:
+			r.flushCoalesced()
+		case <-r.countdownTick:
+			r.handleCountdownTick()
 		case <-ticker.C:
 			r.sendHeartbeat()
+		case <-appPing:
+			r.sendAppPing()
 		}
 	}
 }
 
-func (r *Room) handleRegister(client *websocket.Conn) {
-	if len(r.clients) < r.maxClients {
+// handleBroadcast processes a message taken from the broadcast or priority
+// channel. It runs in room.run().
+func (r *Room) handleBroadcast(broadcastMsg BroadcastMessage) {
+	if !r.allowed(broadcastMsg) {
+		return
+	}
+	if broadcastMsg.control {
+		r.handleControl(broadcastMsg)
+	} else {
+		r.broadcastMessage(broadcastMsg)
+	}
+}
+
+func (r *Room) handleRegister(req joinRequest) {
+	client := req.conn
+	logger := r.connLogger(client)
+	if r.isDuplicate(client) {
+		r.rejectDuplicate(client)
+		return
+	}
+	if r.hasFreeSlot() {
+		clientID := newClientID()
 		r.clients[client] = true
+		r.mu.Lock()
+		r.clientIDs[client] = clientID
+		if r.creatorID == "" {
+			r.creatorID = clientID
+		}
 		r.lastActivity = time.Now()
-		r.server.metrics.mu.Lock()
-		r.server.metrics.activeClients++
-		r.server.metrics.mu.Unlock()
-		log.Printf("Client registered. Total clients: %d", len(r.clients))
+		r.mu.Unlock()
+		r.sessions[client] = newSessionToken()
+		r.server.metrics.activePlayers.Add(1)
+		r.server.metrics.updatePeaks()
+		logger.Info("Client registered", slog.String("room", r.id), slog.String("client", clientID), remoteAttr(client), slog.Int("clients", len(r.clients)))
+		r.logEvent("clientJoined", map[string]interface{}{
+			"clientId": clientID,
+		})
+
+		r.mode.OnClientJoin(r, client)
+		r.runJoinHook(clientID)
+
+		if err := r.writeJSON(client, r.welcome(client)); err != nil {
+			logger.Error("Error sending welcome message", slog.String("room", r.id), slog.String("client", clientID), slog.Any("error", err))
+		}
+		r.replayHistory(client)
+		close(req.ready)
+		r.claimHostIfVacant(client)
+		r.announcePresence("clientJoined", clientID)
+		r.announceIfFull()
+	} else if r.server.config.MaxQueueDepth > 0 || r.locked {
+		r.enqueue(req)
+	} else {
+		logger.Warn("Room is full, rejecting new client", slog.String("room", r.id), remoteAttr(client))
+		r.rejectRegistered(client, &RoomFullError{RoomID: r.id, MaxClients: r.maxClients})
+	}
+}
+
+// welcome returns the message greeting a newly registered player with the
+// room's current state. It runs in room.run(), after the player took its
+// slot but before it claims a vacant host role.
+func (r *Room) welcome(client *websocket.Conn) map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	scores := make(map[string]int, len(r.scores))
+	for clientID, total := range r.scores {
+		scores[clientID] = total
+	}
+	return map[string]interface{}{
+		"type":              "welcome",
+		"clientId":          r.clientIDs[client],
+		"roomId":            r.id,
+		"sessionToken":      r.sessions[client],
+		"requestId":         r.requestID(client),
+		"playerCount":       len(r.clients),
+		"maxClients":        r.maxClients,
+		"currentRound":      r.round,
+		"usedCategoryCount": len(r.usedCategories),
+		"scores":            scores,
+		"isHost":            r.host == nil || r.host == client,
+	}
+}
+
+// announceIfFull tells every player that the game can begin once the last
+// slot was taken. It runs in room.run().
+func (r *Room) announceIfFull() {
+	if len(r.clients) != r.maxClients {
+		return
+	}
+	// Refilling a paused game resumes it right away. A new game only
+	// starts once the countdown ran out, if there is one
+	r.mu.Lock()
+	resumed := r.paused
+	countdown := !resumed && r.server.config.AutoStartCountdown > 0 && r.state == StateWaiting
+	started := !countdown && r.transitionLocked(StatePlaying)
+	if started && resumed {
+		r.paused = false
+		r.startRoundTimerLocked()
+	}
+	r.mu.Unlock()
+
+	r.server.notifyWebhook("roomFull", r.id)
+	broadcastMsg, err := newBroadcast(nil, map[string]interface{}{
+		"type":        "roomFull",
+		"playerCount": len(r.clients),
+	})
+	if err != nil {
+		slog.Error("Error marshalling roomFull message", slog.String("room", r.id), slog.Any("error", err))
+		return
+	}
+	r.broadcastMessage(broadcastMsg)
+
+	if countdown {
+		r.startCountdown()
+	} else if started && !resumed {
+		r.announceGameStart()
+	}
+}
+
+// announceGameStart tells every player that the game begins, so clients can
+// move from the lobby to the game. It runs in room.run().
+func (r *Room) announceGameStart() {
+	r.mu.Lock()
+	round := r.round
+	clientIDs := make([]string, 0, len(r.clientIDs))
+	for _, clientID := range r.clientIDs {
+		clientIDs = append(clientIDs, clientID)
+	}
+	host := r.clientIDs[r.host]
+	r.mu.Unlock()
+	sort.Strings(clientIDs)
+
+	slog.Info("Game started", slog.String("room", r.id), slog.Int("round", round))
+	r.logEvent("gameStarted", map[string]interface{}{
+		"round":     round,
+		"clientIds": clientIDs,
+	})
+	broadcastMsg, err := newBroadcast(nil, map[string]interface{}{
+		"type":      "gameStart",
+		"round":     round,
+		"clientIds": clientIDs,
+		"host":      host,
+	})
+	if err != nil {
+		slog.Error("Error marshalling gameStart message", slog.String("room", r.id), slog.Any("error", err))
+		return
+	}
+	r.broadcastMessage(broadcastMsg)
+}
+
+func (r *Room) handleSpectate(spectator *websocket.Conn) {
+	logger := r.connLogger(spectator)
+	if len(r.spectators) < r.server.config.MaxSpectators {
+		r.mu.Lock()
+		r.spectators[spectator] = true
+		r.mu.Unlock()
+		r.server.metrics.activeSpectators.Add(1)
+		r.server.metrics.updatePeaks()
+		logger.Info("Spectator registered", slog.String("room", r.id), remoteAttr(spectator), slog.Int("spectators", len(r.spectators)))
+		r.logEvent("spectatorJoined", map[string]interface{}{
+			"remote": spectator.RemoteAddr().String(),
+		})
+		r.replayHistory(spectator)
 	} else {
-		log.Println("Room is full. Rejecting new client.")
-		client.Close()
+		r.server.metrics.countError("spectatorsFull")
+		logger.Warn("No spectator slots left, connection rejected", slog.String("room", r.id), remoteAttr(spectator))
+		r.rejectRegistered(spectator, &SpectatorsFullError{RoomID: r.id, MaxSpectators: r.server.config.MaxSpectators})
 	}
 }
 
 func (r *Room) handleUnregister(client *websocket.Conn) {
 	if client == nil {
-		log.Printf("Warning: Attempted to unregister nil client")
+		slog.Warn("Attempted to unregister nil client", slog.String("room", r.id))
 		return
 	}
+	logger := r.connLogger(client)
+	defer r.forgetRequestID(client)
+	defer r.forgetFingerprint(client)
 
-	if _, ok := r.clients[client]; ok {
-		delete(r.clients, client)
-		client.Close()
-		r.lastActivity = time.Now()
-		r.server.metrics.mu.Lock()
-		r.server.metrics.activeClients--
-		r.server.metrics.mu.Unlock()
-		log.Printf("Client unregistered. Total clients: %d", len(r.clients))
+	if r.removeSpectator(client) {
+		closeNormally(client)
+		return
+	}
+
+	r.stopGrace(client)
+
+	if _, ok := r.removeWaiter(client); ok {
+		closeNormally(client)
+		logger.Info("Queued client left", slog.String("room", r.id), remoteAttr(client), slog.Int("queued", len(r.waitingQueue)))
+		return
+	}
+
+	if clientID, ok := r.removePlayer(client); ok {
+		closeNormally(client)
+		logger.Info("Client unregistered", slog.String("room", r.id), slog.String("client", clientID), remoteAttr(client), slog.Int("clients", len(r.clients)))
+		r.announceLeave(client, clientID)
+	}
+	r.handleHostLeft(client)
+}
+
+// removePlayer forgets a player and reserves its slot for a reconnect. It
+// reports the player's client ID, or false if client is not a player. The
+// caller closes the connection and calls announceLeave. It runs in
+// room.run().
+func (r *Room) removePlayer(client *websocket.Conn) (string, bool) {
+	if _, ok := r.clients[client]; !ok {
+		return "", false
+	}
+	clientID := r.clientIDs[client]
+	delete(r.clients, client)
+	r.mu.Lock()
+	delete(r.clientIDs, client)
+	delete(r.pingSent, client)
+	delete(r.appPingSent, client)
+	delete(r.latencies, client)
+	r.forgetTypingLocked(clientID)
+	r.forgetKickVotesLocked(clientID)
+	// Reserve the slot in the same critical section so a new connection
+	// never sees it free in between
+	if token, ok := r.sessions[client]; ok {
+		delete(r.sessions, client)
+		if r.server.config.ReconnectWindow > 0 {
+			r.reserveSlotLocked(token, clientID)
+		}
 	}
+	r.lastActivity = time.Now()
+	r.notifyEmptyLocked()
+	r.mu.Unlock()
+	r.server.metrics.activePlayers.Add(-1)
+	return clientID, true
+}
+
+// announceLeave runs the hooks for a player removed by removePlayer and
+// tells the others it left. It runs in room.run().
+func (r *Room) announceLeave(client *websocket.Conn, clientID string) {
+	r.logEvent("clientLeft", map[string]interface{}{
+		"clientId": clientID,
+	})
+	r.mode.OnClientLeave(r, client)
+	r.runLeaveHook(clientID)
+	r.cancelCountdown(nil, "playerLeft")
+	r.announcePresence("clientLeft", clientID)
+	r.announcePeerLeft(clientID)
+	r.dequeue()
 }
 
 func (r *Room) broadcastMessage(broadcastMsg BroadcastMessage) {
+	if r.stale(broadcastMsg) {
+		return
+	}
+	if broadcastMsg.category != "" {
+		r.useCategory(broadcastMsg.category, broadcastMsg.resetCategories)
+	}
+
+	frameType := broadcastMsg.frameType
+	if frameType == 0 {
+		frameType = websocket.TextMessage
+	}
+
+	// Binary payloads are opaque: they cannot be tagged with the sender,
+	// kept in the JSON history or relayed by the broker
+	binary := frameType == websocket.BinaryMessage
+	if clientID, ok := r.clientIDs[broadcastMsg.sender]; ok && !binary {
+		message, err := withField(broadcastMsg.message, "from", clientID)
+		if err != nil {
+			r.connLogger(broadcastMsg.sender).Error("Error adding sender to message", slog.String("room", r.id), slog.String("client", clientID), slog.Any("error", err))
+		} else {
+			broadcastMsg.message = message
+		}
+	}
+	// Clients tell "waiting" from "playing" by the player count as of
+	// delivery, not as of when the message was queued. The history keeps
+	// the message without it, a replayed count would be stale
+	if !binary {
+		r.recordHistory(broadcastMsg)
+		message, err := withField(broadcastMsg.message, "playerCount", len(r.clients))
+		if err != nil {
+			r.connLogger(broadcastMsg.sender).Error("Error adding player count to message", slog.String("room", r.id), slog.String("type", string(broadcastMsg.msgType)), slog.Any("error", err))
+		} else {
+			broadcastMsg.message = message
+		}
+	}
+
+	// Game messages are batched; everything else goes out right away, after
+	// the pending batch so the order is kept
+	if r.coalescible(broadcastMsg, binary) {
+		r.coalesce(broadcastMsg)
+		r.runMessageHook(broadcastMsg)
+		return
+	}
+	r.flushCoalesced()
+
+	delivered := true
 	for client := range r.clients {
 		if client == nil {
 			continue
 		}
-		if broadcastMsg.msgType != "newCategory" && broadcastMsg.msgType != "allRevealed" && client == broadcastMsg.sender {
+		if !broadcastMsg.includeSender && client == broadcastMsg.sender {
 			continue
 		}
-		err := client.WriteMessage(websocket.TextMessage, broadcastMsg.message)
+		// Writing to a dropped connection would end its grace period early
+		if r.inGrace(client) {
+			continue
+		}
+		if err := r.writeMessage(client, frameType, broadcastMsg.message); err != nil {
+			r.dropClient(client, err)
+			delivered = false
+		}
+	}
+	r.writeSpectators(frameType, broadcastMsg.message)
+
+	if broadcastMsg.ack && r.clients[broadcastMsg.sender] {
+		r.sendAck(broadcastMsg.sender, broadcastMsg.seq, delivered)
+	}
+	if !binary {
+		r.publish(broadcastMsg)
+	}
+	r.runMessageHook(broadcastMsg)
+	r.announceDropped()
+}
+
+// stale reports whether a message waited longer than Config.MaxMessageAge
+// to be delivered. Stale messages are logged and dropped; their sender gets
+// a nack if it asked for an ack.
+func (r *Room) stale(broadcastMsg BroadcastMessage) bool {
+	maxAge := r.server.config.MaxMessageAge
+	if maxAge <= 0 || broadcastMsg.timestamp.IsZero() {
+		return false
+	}
+	age := time.Since(broadcastMsg.timestamp)
+	if age <= maxAge {
+		return false
+	}
+
+	r.connLogger(broadcastMsg.sender).Warn("Dropping stale message", slog.String("room", r.id), slog.String("type", string(broadcastMsg.msgType)), slog.Duration("age", age))
+	r.logEvent("messageDropped", map[string]interface{}{
+		"clientId":  r.clientIDs[broadcastMsg.sender],
+		"type":      broadcastMsg.msgType,
+		"timestamp": broadcastMsg.timestamp,
+	})
+	if broadcastMsg.ack && r.clients[broadcastMsg.sender] {
+		r.sendNack(broadcastMsg.sender, broadcastMsg.seq, "stale")
+	}
+	return true
+}
+
+// closeNormally performs the WebSocket close handshake before closing conn,
+// so the client sees a normal closure rather than an unexpected EOF.
+func closeNormally(conn *websocket.Conn) {
+	conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(time.Second),
+	)
+	conn.Close()
+}
+
+// dropClient removes a player whose connection could not be written to.
+// The others are told once the current write loop is done, see
+// announceDropped, so they do not get "clientLeft" in the middle of a
+// broadcast.
+func (r *Room) dropClient(client *websocket.Conn, err error) {
+	r.connLogger(client).Error("Error broadcasting message", slog.String("room", r.id), slog.String("client", r.clientIDs[client]), slog.Any("error", err))
+	client.Close()
+	if clientID, ok := r.removePlayer(client); ok {
+		r.dropped = append(r.dropped, droppedClient{conn: client, clientID: clientID})
+	}
+}
+
+// droppedClient is a player removed by dropClient that the others have not
+// been told about yet.
+type droppedClient struct {
+	conn     *websocket.Conn
+	clientID string
+}
+
+// announceDropped runs announceLeave and the host handover for the players
+// dropped by the last write loop. It runs in room.run().
+func (r *Room) announceDropped() {
+	for len(r.dropped) > 0 {
+		dropped := r.dropped[0]
+		r.dropped = r.dropped[1:]
+		r.announceLeave(dropped.conn, dropped.clientID)
+		r.handleHostLeft(dropped.conn)
+	}
+}
+
+// writeSpectators sends a frame to every spectator; spectators receive
+// everything the players do.
+func (r *Room) writeSpectators(frameType int, message []byte) {
+	for spectator := range r.spectators {
+		err := r.writeMessage(spectator, frameType, message)
 		if err != nil {
-			log.Printf("Error broadcasting message: %v", err)
-			client.Close()
-			delete(r.clients, client)
+			r.connLogger(spectator).Error("Error broadcasting message to spectator", slog.String("room", r.id), remoteAttr(spectator), slog.Any("error", err))
+			spectator.Close()
+			r.removeSpectator(spectator)
+		}
+	}
+}
+
+// removeSpectator forgets a spectator and reports whether conn was one. The
+// caller closes the connection. It runs in room.run().
+func (r *Room) removeSpectator(conn *websocket.Conn) bool {
+	if _, ok := r.spectators[conn]; !ok {
+		return false
+	}
+	r.mu.Lock()
+	delete(r.spectators, conn)
+	r.mu.Unlock()
+	r.server.metrics.activeSpectators.Add(-1)
+	r.connLogger(conn).Info("Spectator unregistered", slog.String("room", r.id), remoteAttr(conn), slog.Int("spectators", len(r.spectators)))
+	r.logEvent("spectatorLeft", map[string]interface{}{
+		"remote": conn.RemoteAddr().String(),
+	})
+	return true
+}
+
+// publish lets clients of this room on other instances see the message too.
+func (r *Room) publish(broadcastMsg BroadcastMessage) {
+	if r.server.broker == nil || broadcastMsg.remote {
+		return
+	}
+	if err := r.server.broker.Publish(r.id, broadcastMsg.message); err != nil {
+		slog.Error("Error publishing message", slog.String("room", r.id), slog.Any("error", err))
+	}
+}
+
+// handleRemoteMessage delivers a message published by another instance to
+// the local clients. Categories picked elsewhere are marked as used here too
+// so both instances keep drawing unique categories.
+func (r *Room) handleRemoteMessage(message []byte) {
+	var msg struct {
+		Type        MessageType `json:"type"`
+		Value       string      `json:"value"`
+		NewCategory string      `json:"newCategory"`
+	}
+	if err := json.Unmarshal(message, &msg); err != nil {
+		slog.Warn("Ignoring malformed remote message", slog.String("room", r.id), slog.Any("error", err))
+		return
+	}
+	// The sender is on the other instance, so nobody here is left out
+	broadcastMsg := BroadcastMessage{
+		message:       message,
+		msgType:       msg.Type,
+		remote:        true,
+		includeSender: true,
+	}
+	switch msg.Type {
+	case "newCategory":
+		broadcastMsg.category = msg.Value
+	case "categorySkipped":
+		broadcastMsg.category = msg.NewCategory
+	}
+	r.broadcastMessage(broadcastMsg)
+}
+
+// useCategory records a category as drawn in this room. reset starts the
+// used list over, after every category has been drawn. It runs in room.run().
+func (r *Room) useCategory(category string, reset bool) {
+	r.mu.Lock()
+	if reset {
+		r.usedCategories = nil
+	}
+	r.usedCategories = append(r.usedCategories, category)
+	r.mu.Unlock()
+}
+
+// writeJSON encodes payload and writes it to a single connection.
+func (r *Room) writeJSON(conn *websocket.Conn, payload map[string]interface{}) error {
+	message, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return r.writeMessage(conn, websocket.TextMessage, message)
+}
+
+// handleInactivity disconnects a player whose inactivity timer fired. It runs
+// on the timer's goroutine.
+func (r *Room) handleInactivity(conn *websocket.Conn) {
+	slog.Info("Client inactive, disconnecting", slog.String("room", r.id), remoteAttr(conn))
+	if err := r.writeJSON(conn, map[string]interface{}{
+		"type": "inactivityTimeout",
+	}); err != nil {
+		slog.Error("Error sending inactivityTimeout message", slog.String("room", r.id), remoteAttr(conn), slog.Any("error", err))
+	}
+	conn.Close()
+
+	select {
+	case r.unregister <- conn:
+	case <-r.ctx.Done():
+	}
+}
+
+// withField returns a copy of the JSON object in message with key set to value.
+func withField(message []byte, key string, value interface{}) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(message, &fields); err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	fields[key] = encoded
+
+	return json.Marshal(fields)
+}
+
+// recordHistory keeps the last Config.HistorySize broadcast messages so late
+// joiners can catch up on the current game. Transient messages are skipped.
+func (r *Room) recordHistory(broadcastMsg BroadcastMessage) {
+	if r.server.config.HistorySize <= 0 || transientMessageTypes[broadcastMsg.msgType] {
+		return
+	}
+
+	r.history = append(r.history, json.RawMessage(broadcastMsg.message))
+	if len(r.history) > r.server.config.HistorySize {
+		r.history = r.history[len(r.history)-r.server.config.HistorySize:]
+	}
+}
+
+// replayHistory sends the recorded history to a newly registered connection.
+func (r *Room) replayHistory(conn *websocket.Conn) {
+	for _, message := range r.history {
+		if err := r.writeMessage(conn, websocket.TextMessage, message); err != nil {
+			slog.Error("Error replaying history", slog.String("room", r.id), remoteAttr(conn), slog.Any("error", err))
+			return
 		}
 	}
 }
@@ -363,67 +1663,155 @@ func (r *Room) sendHeartbeat() {
 	})
 
 	for client := range r.clients {
-		if client == nil {
+		if client == nil || r.inGrace(client) {
 			continue
 		}
-		err := client.WriteMessage(websocket.PingMessage, heartbeat)
+		r.mu.Lock()
+		r.pingSent[client] = time.Now()
+		r.mu.Unlock()
+		err := r.writeMessage(client, websocket.PingMessage, heartbeat)
 		if err != nil {
-			r.unregister <- client
+			r.handleUnregister(client)
+		}
+	}
+
+	for spectator := range r.spectators {
+		err := r.writeMessage(spectator, websocket.PingMessage, heartbeat)
+		if err != nil {
+			spectator.Close()
+			r.removeSpectator(spectator)
 		}
 	}
 }
 
-func (s *Server) cleanupEmptyRooms() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// expired reports whether cleanupEmptyRooms should remove room according to
+// Config.CleanupStrategy: "empty" removes empty rooms, "timeout" removes
+// rooms idle for longer than Config.RoomTimeout, and "both", the default,
+// removes either. Rooms nobody joined yet are not empty until they are
+// older than Config.RoomTimeout, so a room created ahead of time survives
+// until its players arrive.
+func (s *Server) expired(room *Room, now time.Time) bool {
+	timedOut := now.Sub(room.LastActivity()) > s.config.RoomTimeout
+	empty := room.IsEmpty()
+	if empty && room.awaitingFirstPlayer() {
+		empty = now.Sub(room.createdAt) > s.config.RoomTimeout
+	}
+	switch s.config.CleanupStrategy {
+	case "empty":
+		return empty
+	case "timeout":
+		return timedOut
+	default:
+		return empty || timedOut
+	}
+}
 
+func (s *Server) cleanupEmptyRooms() {
 	now := time.Now()
-	for id, room := range s.rooms {
-		if len(room.clients) == 0 || now.Sub(room.lastActivity) > s.config.RoomTimeout {
-			close(room.broadcast)
-			close(room.register)
-			close(room.unregister)
-			close(room.done)
-			delete(s.rooms, id)
-			s.metrics.mu.Lock()
-			s.metrics.activeRooms--
-			s.metrics.mu.Unlock()
-			log.Printf("Cleaned up room: %s", id)
+	s.rooms.Range(func(key, value any) bool {
+		id, room := key.(string), value.(*Room)
+		if s.expired(room, now) {
+			reason := "empty"
+			if !room.IsEmpty() {
+				reason = "timeout"
+			}
+			// The room may have been closed by an admin in the meantime
+			if !s.removeRoom(room, reason) {
+				return true
+			}
+			slog.Info("Cleaned up room", slog.String("room", id), slog.Duration("age", now.Sub(room.createdAt)))
 		}
-	}
+		return true
+	})
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
 	close(s.shutdown)
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	// Each room closes its connections from room.run(), which owns them.
+	// room.ctx is cancelled once it has
+	var rooms []*Room
+	s.rooms.Range(func(_, value any) bool {
+		room := value.(*Room)
+		room.Close("server shutdown")
+		rooms = append(rooms, room)
+		return true
+	})
+	for _, room := range rooms {
+		select {
+		case <-room.ctx.Done():
+		case <-ctx.Done():
+		}
+	}
+
+	// Cancelling the server context stops every room and reader goroutine.
+	// Wait for the readers before closing what they might still use
+	s.cancel()
+	readersDone := make(chan struct{})
+	go func() {
+		s.readers.Wait()
+		close(readersDone)
+	}()
+	select {
+	case <-readersDone:
+	case <-ctx.Done():
+		slog.Warn("Timed out waiting for connections to finish", slog.Any("error", ctx.Err()))
+	}
 
-	for _, room := range s.rooms {
-		close(room.done)
-		for client := range room.clients {
-			client.WriteControl(
-				websocket.CloseMessage,
-				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutdown"),
-				time.Now().Add(time.Second),
-			)
-			client.Close()
+	if s.store != nil {
+		if err := s.store.Close(); err != nil {
+			slog.Error("Error closing database", slog.Any("error", err))
 		}
 	}
+	if s.broker != nil {
+		return s.broker.Close()
+	}
 	return nil
 }
 
 func main() {
-	config := Config{
-		Port:            "8080",
-		MaxClients:      2,
-		CleanupInterval: 5 * time.Minute,
-		RoomTimeout:     30 * time.Minute,
-		ReadTimeout:     10 * time.Second,
-		WriteTimeout:    10 * time.Second,
+	configPath := flag.String("config", "", "path to a JSON config file")
+	validateConfig := flag.Bool("config-validate", false, "load the config, print a summary and exit")
+	flag.Parse()
+
+	// Precedence: environment > config file > defaults
+	var (
+		config     Config
+		overridden []string
+		envErr     error
+	)
+	if *configPath == "" {
+		config, overridden, envErr = NewConfigFromEnv()
+	} else {
+		fileConfig, err := LoadConfigFile(*configPath)
+		if err != nil {
+			slog.Error("Error loading config file", slog.String("path", *configPath), slog.Any("error", err))
+			os.Exit(1)
+		}
+		config, overridden, envErr = applyEnv(fileConfig)
+	}
+	if *validateConfig {
+		if envErr != nil {
+			fmt.Fprintln(os.Stderr, envErr)
+		}
+		fmt.Print(config.Summary())
+		return
+	}
+
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: parseLogLevel(config.LogLevel),
+	})))
+	if len(overridden) > 0 {
+		slog.Info("Config overridden by environment", slog.Any("variables", overridden))
+	}
+	if envErr != nil {
+		slog.Warn("Ignoring invalid environment variables", slog.Any("error", envErr))
 	}
 
 	server := NewServer(config)
+	if server.store != nil {
+		server.restoreRooms()
+	}
 
 	// Setup HTTP server
 	srv := &http.Server{
@@ -437,11 +1825,32 @@ func main() {
 
 	// Add basic metrics endpoint
 	mux.HandleFunc("/metrics", server.handleMetrics)
-
-	// Add health check endpoint
+	mux.HandleFunc("/metrics/rooms", server.handleRoomMetrics)
+	mux.HandleFunc("GET /metrics/categories", server.handleCategoryMetrics)
+	mux.HandleFunc("/metrics/prometheus", server.handlePrometheusMetrics)
+	mux.HandleFunc("GET /stats", server.handleStats)
+
+	// Add room listing endpoint
+	mux.HandleFunc("GET /rooms", server.handleListRooms)
+	mux.HandleFunc("POST /rooms", server.handleCreateRoom)
+
+	// Add admin endpoints
+	mux.HandleFunc("GET /admin/categories", server.requireAdmin(server.handleGetCategories))
+	mux.HandleFunc("PUT /admin/categories", server.requireAdmin(server.handlePutCategories))
+	mux.HandleFunc("DELETE /admin/rooms/{roomID}", server.requireAdmin(server.handleCloseRoom))
+	mux.HandleFunc("POST /admin/rooms/{roomID}/kick", server.requireAdmin(server.handleKickClient))
+	mux.HandleFunc("GET /admin/rooms/{roomID}/snapshot", server.requireAdmin(server.handleGetSnapshot))
+	mux.HandleFunc("POST /admin/rooms/{roomID}/snapshot", server.requireAdmin(server.handleRestoreSnapshot))
+	mux.HandleFunc("GET /admin/rooms/{roomID}/events", server.requireAdmin(server.handleGetEvents))
+	mux.HandleFunc("POST /admin/broadcast", server.requireAdmin(server.handleAnnouncement))
+	mux.HandleFunc("GET /admin/reports", server.requireAdmin(server.handleGetReports))
+
+	// Liveness probe: healthy as long as the process is alive
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
+	// Readiness probe
+	mux.HandleFunc("/ready", server.handleReady)
 
 	// Setup static file server
 	fileServer := http.FileServer(http.FS(server.distFS))
@@ -474,9 +1883,39 @@ func main() {
 
 	// Start server
 	go func() {
-		log.Printf("Server starting on 0.0.0.0:%s", config.Port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Error starting server: %v", err)
+		slog.Info("Server starting", slog.String("addr", srv.Addr), slog.Bool("tls", config.tlsEnabled()))
+		var err error
+		if config.tlsEnabled() {
+			err = srv.ListenAndServeTLS(config.TLSCertFile, config.TLSKeyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			slog.Error("Error starting server", slog.Any("error", err))
+			os.Exit(1)
+		}
+	}()
+
+	// Redirect plain HTTP to HTTPS when TLS is enabled
+	var redirectSrv *http.Server
+	if config.tlsEnabled() && config.RedirectPort != "" {
+		redirectSrv = newRedirectServer(config)
+		go func() {
+			slog.Info("HTTP redirect starting", slog.String("addr", redirectSrv.Addr))
+			if err := redirectSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("Error starting HTTP redirect", slog.Any("error", err))
+			}
+		}()
+	}
+
+	// Reload categories on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := server.ReloadCategories(); err != nil {
+				slog.Error("Error reloading categories, keeping the current list", slog.Any("error", err))
+			}
 		}
 	}()
 
@@ -490,25 +1929,122 @@ func main() {
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Printf("Error during server shutdown: %v", err)
+		slog.Error("Error during server shutdown", slog.Any("error", err))
 	}
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Printf("Error during HTTP server shutdown: %v", err)
+		slog.Error("Error during HTTP server shutdown", slog.Any("error", err))
+	}
+	if redirectSrv != nil {
+		if err := redirectSrv.Shutdown(ctx); err != nil {
+			slog.Error("Error during HTTP redirect shutdown", slog.Any("error", err))
+		}
 	}
 
-	log.Println("Server stopped gracefully")
+	slog.Info("Server stopped gracefully")
 }
 
 func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
-	s.metrics.mu.Lock()
-	defer s.metrics.mu.Unlock()
+	var avgRTT float64
+	if rttCount := s.metrics.rttCount.Load(); rttCount > 0 {
+		avgRTT = float64(s.metrics.rttTotal.Load()) / float64(rttCount) / float64(time.Millisecond)
+	}
 
+	activePlayers, activeSpectators := s.metrics.activePlayers.Load(), s.metrics.activeSpectators.Load()
 	metrics := map[string]interface{}{
-		"active_rooms":   s.metrics.activeRooms,
-		"active_clients": s.metrics.activeClients,
-		"messages_total": s.metrics.messagesTotal,
-		"error_count":    s.metrics.errorCount,
+		"active_rooms":      s.metrics.activeRooms.Load(),
+		"active_clients":    activePlayers + activeSpectators,
+		"active_players":    activePlayers,
+		"active_spectators": activeSpectators,
+		"messages_total":    s.metrics.messagesTotal.Load(),
+		"error_count":       s.metrics.errorCount.Load(),
+		"blocked_sends":     s.metrics.blockedSends.Load(),
+		"avg_rtt_ms":        avgRTT,
 	}
 
 	json.NewEncoder(w).Encode(metrics)
 }
+
+// handleReady answers readiness probes: 200 once the server can host games,
+// 503 until then.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !s.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "starting",
+			"reason": "categories not loaded",
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "ready",
+	})
+}
+
+// handleRoomMetrics reports per room metrics, oldest room first, so rooms
+// that have been running suspiciously long stand out.
+func (s *Server) handleRoomMetrics(w http.ResponseWriter, r *http.Request) {
+	rooms := s.ListRooms()
+	metrics := make([]map[string]interface{}, 0, len(rooms))
+	for _, info := range rooms {
+		metrics = append(metrics, map[string]interface{}{
+			"room_id":         info.ID,
+			"players":         info.PlayerCount,
+			"spectators":      info.SpectatorCount,
+			"used_categories": info.UsedCategories,
+			"avg_latency_ms":  info.AvgLatencyMs,
+			"p99_latency_ms":  info.P99LatencyMs,
+			"age_seconds":     time.Since(info.CreatedAt).Seconds(),
+		})
+	}
+
+	json.NewEncoder(w).Encode(metrics)
+}
+
+// createRoomRequest is the body accepted by POST /rooms.
+type createRoomRequest struct {
+	RoomID       string `json:"roomId"`
+	MaxClients   int    `json:"maxClients"`
+	CategoryPack string `json:"categoryPack"`
+	Password     string `json:"password"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+}
+
+func (s *Server) handleCreateRoom(w http.ResponseWriter, r *http.Request) {
+	var req createRoomRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if err := s.validateRoomID(req.RoomID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name, description, truncated := normalizeRoomMeta(req.Name, req.Description)
+	_, created, err := s.getOrCreateRoom(req.RoomID, RoomOptions{
+		MaxClients:   req.MaxClients,
+		CategoryPack: req.CategoryPack,
+		Password:     req.Password,
+		Name:         name,
+		Description:  description,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	response := map[string]interface{}{
+		"roomId":  req.RoomID,
+		"created": created,
+	}
+	if created && len(truncated) > 0 {
+		response["truncated"] = truncated
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestServer serves the WebSocket endpoint of a server whose default
+// config was changed by configure. It returns the server and the endpoint's
+// URL.
+func newTestServer(t *testing.T, configure func(*Config)) (*Server, string) {
+	t.Helper()
+	config := defaultConfig()
+	config.ReadTimeout = time.Second
+	if configure != nil {
+		configure(&config)
+	}
+	s := NewServer(config)
+	ts := httptest.NewServer(http.HandlerFunc(s.handleConnections))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.Shutdown(ctx)
+		ts.Close()
+	})
+	return s, "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+}
+
+// dial opens a WebSocket connection to url with the given query.
+func dial(t *testing.T, url, query string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?"+query, nil)
+	if err != nil {
+		t.Fatalf("dial %s: %v", query, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// send writes msg to conn.
+func send(t *testing.T, conn *websocket.Conn, msg map[string]interface{}) {
+	t.Helper()
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatalf("sending %v: %v", msg["type"], err)
+	}
+}
+
+// expect reads from conn until a message of type msgType arrives and returns
+// it.
+func expect(t *testing.T, conn *websocket.Conn, msgType string) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		for _, msg := range readMessages(t, conn, msgType) {
+			if msg["type"] == msgType {
+				return msg
+			}
+		}
+	}
+}
+
+// readMessages reads the next frame from conn, unwrapping batched frames.
+// waitingFor is only used in failure messages.
+func readMessages(t *testing.T, conn *websocket.Conn, waitingFor string) []map[string]interface{} {
+	t.Helper()
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("waiting for %s: %v", waitingFor, err)
+	}
+	var batch []map[string]interface{}
+	if json.Unmarshal(data, &batch) != nil {
+		var msg map[string]interface{}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("waiting for %s: %v", waitingFor, err)
+		}
+		batch = []map[string]interface{}{msg}
+	}
+	return batch
+}
+
+// expectClosed reads from conn until the server closes it.
+func expectClosed(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Fatalf("waiting for close: %v", err)
+			}
+			return
+		}
+	}
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Now()
+	const timeout = 30 * time.Minute
+	tests := []struct {
+		name     string
+		strategy string
+		players  int
+		joined   bool
+		age      time.Duration
+		idle     time.Duration
+		want     bool
+	}{
+		{"new room nobody joined yet", "both", 0, false, time.Minute, time.Minute, false},
+		{"room nobody joined for too long", "both", 0, false, timeout + time.Minute, timeout + time.Minute, true},
+		{"room everyone left", "both", 0, true, time.Minute, 0, true},
+		{"active room", "both", 2, true, time.Hour, time.Minute, false},
+		{"idle room", "both", 2, true, time.Hour, timeout + time.Minute, true},
+		{"empty strategy keeps idle room", "empty", 2, true, time.Hour, timeout + time.Minute, false},
+		{"empty strategy keeps new room", "empty", 0, false, time.Minute, time.Minute, false},
+		{"empty strategy removes left room", "empty", 0, true, time.Minute, 0, true},
+		{"timeout strategy keeps left room", "timeout", 0, true, time.Minute, 0, false},
+		{"timeout strategy removes idle room", "timeout", 1, true, time.Hour, timeout + time.Minute, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{config: Config{CleanupStrategy: tt.strategy, RoomTimeout: timeout}}
+			room := &Room{
+				clientIDs:    make(map[*websocket.Conn]string),
+				createdAt:    now.Add(-tt.age),
+				lastActivity: now.Add(-tt.idle),
+			}
+			for i := 0; i < tt.players; i++ {
+				room.clientIDs[&websocket.Conn{}] = "client"
+			}
+			if tt.joined {
+				room.creatorID = "creator"
+			}
+			if got := s.expired(room, now); got != tt.want {
+				t.Errorf("expired = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Late joiners are replayed the game messages, without the player count of
+// the time, but not presence or other transient messages.
+func TestHistorySkipsTransientMessages(t *testing.T) {
+	s, url := newTestServer(t, nil)
+	if _, _, err := s.getOrCreateRoom("history", RoomOptions{MaxClients: 3}); err != nil {
+		t.Fatalf("getOrCreateRoom: %v", err)
+	}
+	first := dial(t, url, "room=history")
+	expect(t, first, "welcome")
+	second := dial(t, url, "room=history")
+	expect(t, second, "welcome")
+	send(t, first, map[string]interface{}{"type": "playerInput", "value": "Hund"})
+	expect(t, second, "playerInput")
+
+	late := dial(t, url, "room=history")
+	welcome := expect(t, late, "welcome")
+	var replayed []map[string]interface{}
+	late.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		for _, msg := range readMessages(t, late, "clientJoined") {
+			if msg["type"] == "clientJoined" && msg["clientId"] == welcome["clientId"] {
+				for _, msg := range replayed {
+					if msg["type"] == "playerInput" {
+						return
+					}
+				}
+				t.Fatalf("replayed %v, want the playerInput", replayed)
+			}
+			if msgType, _ := msg["type"].(string); transientMessageTypes[MessageType(msgType)] {
+				t.Errorf("replayed transient message %v", msg)
+			}
+			if _, ok := msg["playerCount"]; ok {
+				t.Errorf("replayed message has a playerCount: %v", msg)
+			}
+			replayed = append(replayed, msg)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+)
+
+// MessageType is the "type" field of a client message.
+type MessageType string
+
+// Client messages the server acts on.
+const (
+	TypeNewCategory     MessageType = "newCategory"
+	TypeReveal          MessageType = "reveal"
+	TypeSkipCategory    MessageType = "skipCategory"
+	TypeNewRound        MessageType = "newRound"
+	TypeScore           MessageType = "score"
+	TypeResetGame       MessageType = "resetGame"
+	TypeReconnect       MessageType = "reconnect"
+	TypeSetRoomMeta     MessageType = "setRoomMeta"
+	TypeTransferHost    MessageType = "transferHost"
+	TypeSetName         MessageType = "setName"
+	TypeChat            MessageType = "chat"
+	TypeRoomInfo        MessageType = "roomInfo"
+	TypePong            MessageType = "pong"
+	TypeSetAllowedTypes MessageType = "setAllowedTypes"
+	TypeGameEnd         MessageType = "gameEnd"
+	TypeCancelCountdown MessageType = "cancelCountdown"
+	TypeKickVote        MessageType = "kickVote"
+	TypeReport          MessageType = "report"
+	TypeTyping          MessageType = "typing"
+)
+
+// Client messages relayed to the peers as they are.
+const (
+	TypePlayerInput MessageType = "playerInput"
+	TypeStreak      MessageType = "streak"
+	TypeResetStreak MessageType = "resetStreak"
+)
+
+// Control messages the server queues for room.run() itself. Clients cannot
+// send them.
+const (
+	TypeLockRoom        MessageType = "lockRoom"
+	TypeUnlockRoom      MessageType = "unlockRoom"
+	TypePostGameTimeout MessageType = "postGameTimeout"
+)
+
+// ValidMessageTypes lists the message types clients may send; anything else
+// is rejected with "unknownMessageType". Game modes that understand more
+// types add them here before the server starts.
+var ValidMessageTypes = map[MessageType]bool{
+	TypeNewCategory:     true,
+	TypeReveal:          true,
+	TypeSkipCategory:    true,
+	TypeNewRound:        true,
+	TypeScore:           true,
+	TypeResetGame:       true,
+	TypeReconnect:       true,
+	TypeSetRoomMeta:     true,
+	TypeTransferHost:    true,
+	TypeSetName:         true,
+	TypeChat:            true,
+	TypeRoomInfo:        true,
+	TypePong:            true,
+	TypeSetAllowedTypes: true,
+	TypeGameEnd:         true,
+	TypeCancelCountdown: true,
+	TypeKickVote:        true,
+	TypeReport:          true,
+	TypeTyping:          true,
+	TypePlayerInput:     true,
+	TypeStreak:          true,
+	TypeResetStreak:     true,
+}
+
+// transientMessageTypes are broadcasts that only matter at the moment they
+// are sent. They are kept out of the history replayed to late joiners, who
+// learn the current players, countdown and game state from "welcome".
+var transientMessageTypes = map[MessageType]bool{
+	"heartbeat":          true,
+	"clientJoined":       true,
+	"clientLeft":         true,
+	"peerLeft":           true,
+	"waitingForPlayers":  true,
+	TypeTyping:           true,
+	"peerTyping":         true,
+	"kickVoted":          true,
+	"kickVoteResult":     true,
+	"countdown":          true,
+	"countdownCancelled": true,
+	"roomFull":           true,
+	"gameStart":          true,
+	"serverAnnouncement": true,
+}
+
+// sendUnknownMessageType tells conn that the server does not know msgType.
+func (r *Room) sendUnknownMessageType(conn *websocket.Conn, msgType MessageType) {
+	if err := r.writeJSON(conn, map[string]interface{}{
+		"type":    "unknownMessageType",
+		"msgType": msgType,
+	}); err != nil {
+		slog.Error("Error sending unknownMessageType message", slog.String("room", r.id), remoteAttr(conn), slog.Any("error", err))
+	}
+}
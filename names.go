@@ -0,0 +1,100 @@
+package main
+
+import (
+	"log/slog"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+const maxClientNameLength = 32
+
+// validClientName reports whether name is 1 to maxClientNameLength printable
+// characters.
+func validClientName(name string) bool {
+	n := utf8.RuneCountInString(name)
+	if n < 1 || n > maxClientNameLength || !utf8.ValidString(name) {
+		return false
+	}
+	for _, c := range name {
+		if !unicode.IsPrint(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// uniqueNameLocked returns name, or name with the lowest numeric suffix that
+// no other client in the room uses. r.mu must be held.
+func (r *Room) uniqueNameLocked(clientID, name string) string {
+	taken := func(candidate string) bool {
+		for id, other := range r.clientNames {
+			if id != clientID && other == candidate {
+				return true
+			}
+		}
+		return false
+	}
+
+	candidate := name
+	for n := 2; taken(candidate); n++ {
+		suffix := strconv.Itoa(n)
+		base, _ := truncateRunes(name, maxClientNameLength-len(suffix))
+		candidate = base + suffix
+	}
+	return candidate
+}
+
+// namesLocked returns a copy of the display names by client ID. r.mu must be
+// held.
+func (r *Room) namesLocked() map[string]string {
+	names := make(map[string]string, len(r.clientNames))
+	for clientID, name := range r.clientNames {
+		names[clientID] = name
+	}
+	return names
+}
+
+// setName sets the display name of the sending client and announces it. A
+// name already used in the room gets a numeric suffix.
+func (r *Room) setName(conn *websocket.Conn, msg map[string]interface{}) {
+	clientID := r.clientID(conn)
+	if clientID == "" {
+		return
+	}
+
+	name, _ := msg["name"].(string)
+	if !validClientName(name) {
+		if err := r.writeJSON(conn, map[string]interface{}{
+			"type":  "error",
+			"code":  "invalidName",
+			"limit": maxClientNameLength,
+		}); err != nil {
+			slog.Error("Error sending invalidName message", slog.String("room", r.id), slog.String("client", clientID), slog.Any("error", err))
+		}
+		return
+	}
+
+	r.mu.Lock()
+	name = r.uniqueNameLocked(clientID, name)
+	r.clientNames[clientID] = name
+	r.logEventLocked("nameSet", map[string]interface{}{
+		"clientId": clientID,
+	})
+	r.mu.Unlock()
+
+	r.broadcastJSON(conn, map[string]interface{}{
+		"type":     "nameSet",
+		"clientId": clientID,
+		"name":     name,
+	})
+}
+
+// forgetName drops the display name of a client that left for good.
+func (r *Room) forgetName(clientID string) {
+	r.mu.Lock()
+	delete(r.clientNames, clientID)
+	r.mu.Unlock()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// checkOrigin matches the Origin header of a WebSocket upgrade against
+// Config.AllowedOrigins. An empty allowlist accepts every origin. Entries are
+// either full origins ("https://example.com"), host names ("example.com") or
+// wildcards ("*.example.com") matching any subdomain.
+func (s *Server) checkOrigin(r *http.Request) bool {
+	if len(s.config.AllowedOrigins) == 0 {
+		return true
+	}
+
+	// Browsers always send an Origin; only non-browser clients omit it
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err == nil {
+		for _, allowed := range s.config.AllowedOrigins {
+			if originAllowed(u, allowed) {
+				return true
+			}
+		}
+	}
+
+	// The failed upgrade is counted in Metrics.errorCount by handleConnections
+	slog.Warn("Rejected connection from disallowed origin", slog.String("origin", origin), slog.String("remote", r.RemoteAddr))
+	return false
+}
+
+func originAllowed(origin *url.URL, allowed string) bool {
+	allowed = strings.ToLower(strings.TrimSuffix(allowed, "/"))
+	host := strings.ToLower(origin.Hostname())
+
+	switch {
+	case strings.Contains(allowed, "://"):
+		return strings.ToLower(origin.Scheme+"://"+origin.Host) == allowed
+	case strings.HasPrefix(allowed, "*."):
+		return strings.HasSuffix(host, allowed[1:])
+	default:
+		return host == allowed
+	}
+}
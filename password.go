@@ -0,0 +1,40 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// hashPassword returns the bcrypt hash of a room password. Passwords longer
+// than 72 bytes are rejected.
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// checkPassword reports whether password matches a bcrypt hash. It is slow
+// on purpose, so never call it from room.run().
+func checkPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// authenticate checks the auth message a client must send first when
+// joining a password protected room. It runs on the connection's goroutine
+// so the slow hash comparison never holds up the room. Clients that fail are
+// sent authFailed.
+func (r *Room) authenticate(conn *websocket.Conn, msg map[string]interface{}) bool {
+	password, _ := msg["password"].(string)
+	if msg["type"] == "auth" && checkPassword(r.passwordHash, password) {
+		return true
+	}
+
+	slog.Warn("Authentication failed", slog.String("room", r.id), remoteAttr(conn))
+	if err := r.writeJSON(conn, map[string]interface{}{
+		"type": "authFailed",
+	}); err != nil {
+		slog.Error("Error sending authFailed message", slog.String("room", r.id), remoteAttr(conn), slog.Any("error", err))
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := hashPassword("secret")
+	if err != nil {
+		t.Fatalf("hashPassword: %v", err)
+	}
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		want     bool
+	}{
+		{"correct password", hash, "secret", true},
+		{"wrong password", hash, "Secret", false},
+		{"empty password", hash, "", false},
+		{"malformed hash", "not a hash", "secret", false},
+		{"empty hash", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkPassword(tt.hash, tt.password); got != tt.want {
+				t.Errorf("checkPassword = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashPasswordTooLong(t *testing.T) {
+	if _, err := hashPassword(strings.Repeat("x", 73)); err == nil {
+		t.Error("hashPassword accepted a password longer than 72 bytes")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name     string
+		first    map[string]interface{}
+		wantType string
+	}{
+		{"correct password", map[string]interface{}{"type": "auth", "password": "secret"}, "welcome"},
+		{"wrong password", map[string]interface{}{"type": "auth", "password": "guess"}, "authFailed"},
+		{"no auth message", map[string]interface{}{"type": "chat", "message": "hi"}, "authFailed"},
+		{"reconnect with unknown token", map[string]interface{}{"type": "reconnect", "token": "unknown"}, "reconnectFailed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, url := newTestServer(t, nil)
+			if _, _, err := s.getOrCreateRoom("locked", RoomOptions{Password: "secret"}); err != nil {
+				t.Fatalf("getOrCreateRoom: %v", err)
+			}
+			conn := dial(t, url, "room=locked")
+			send(t, conn, tt.first)
+			expect(t, conn, tt.wantType)
+			if tt.wantType != "welcome" {
+				expectClosed(t, conn)
+			}
+		})
+	}
+}
+
+// A newcomer to a full password protected room with a reserved slot sends
+// auth, not reconnect, as its first message.
+func TestAuthenticateFullRoomWithReservedSlot(t *testing.T) {
+	s, url := newTestServer(t, func(config *Config) {
+		config.DisconnectGrace = 0
+	})
+	if _, _, err := s.getOrCreateRoom("locked", RoomOptions{MaxClients: 2, Password: "secret"}); err != nil {
+		t.Fatalf("getOrCreateRoom: %v", err)
+	}
+	auth := map[string]interface{}{"type": "auth", "password": "secret"}
+
+	first := dial(t, url, "room=locked")
+	send(t, first, auth)
+	expect(t, first, "welcome")
+	second := dial(t, url, "room=locked")
+	send(t, second, auth)
+	welcome := expect(t, second, "welcome")
+	second.Close()
+	expect(t, first, "clientLeft")
+
+	newcomer := dial(t, url, "room=locked")
+	send(t, newcomer, auth)
+	expect(t, newcomer, "queued")
+
+	// The player who left reclaims the slot without the password
+	returning := dial(t, url, "room=locked")
+	send(t, returning, map[string]interface{}{"type": "reconnect", "token": welcome["sessionToken"]})
+	if msg := expect(t, returning, "reconnected"); msg["clientId"] != welcome["clientId"] {
+		t.Errorf("reconnected as %v, want %v", msg["clientId"], welcome["clientId"])
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import "log/slog"
+
+// announcePresence tells every player, including the one that just joined,
+// that a player joined or left. It runs in room.run(), which delivers the
+// message right away, ahead of anything still queued for the room.
+func (r *Room) announcePresence(msgType, clientID string) {
+	r.mu.Lock()
+	name := r.clientNames[clientID]
+	r.mu.Unlock()
+
+	broadcastMsg, err := newBroadcast(nil, map[string]interface{}{
+		"type":        msgType,
+		"clientId":    clientID,
+		"name":        name,
+		"playerCount": len(r.clients),
+	})
+	if err != nil {
+		slog.Error("Error marshalling presence message", slog.String("room", r.id), slog.String("type", msgType), slog.Any("error", err))
+		return
+	}
+	r.broadcastMessage(broadcastMsg)
+}
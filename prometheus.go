@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// counter is a Prometheus counter whose value can also be read back for
+// handleMetrics and Stats.
+type counter struct {
+	value atomic.Int64
+	prom  prometheus.Counter
+}
+
+func (c *counter) Add(n int64) {
+	c.value.Add(n)
+	c.prom.Add(float64(n))
+}
+
+func (c *counter) Load() int64 {
+	return c.value.Load()
+}
+
+// gauge is a Prometheus gauge whose value can also be read back for
+// handleMetrics and Stats.
+type gauge struct {
+	value atomic.Int64
+	prom  prometheus.Gauge
+}
+
+func (g *gauge) Add(n int64) {
+	g.value.Add(n)
+	g.prom.Add(float64(n))
+}
+
+func (g *gauge) Load() int64 {
+	return g.value.Load()
+}
+
+// newMetrics creates the server metrics and registers them with a registry
+// of their own, which handlePrometheusMetrics serves.
+func newMetrics(startedAt time.Time) *Metrics {
+	m := &Metrics{
+		registry:     prometheus.NewRegistry(),
+		errorsByType: make(map[string]int64),
+	}
+	m.activeRooms.prom = m.newGauge("active_rooms", "Number of rooms currently open.")
+	m.activePlayers.prom = m.newGauge("active_players", "Number of players currently connected.")
+	m.activeSpectators.prom = m.newGauge("active_spectators", "Number of spectators currently connected.")
+	m.messagesTotal.prom = m.newCounter("messages_total", "Total number of messages processed.")
+	m.errorCount.prom = m.newCounter("error_count", "Total number of connection and room errors.")
+	m.blockedSends.prom = m.newCounter("blocked_sends", "Total number of sends to a room that waited longer than the slow send threshold.")
+	m.roomsCreated.prom = m.newCounter("rooms_created_total", "Total number of rooms created.")
+	m.clientsConnected.prom = m.newCounter("clients_connected_total", "Total number of WebSocket clients connected.")
+	m.errorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "errors_total",
+		Help: "Total number of connection and room errors by type.",
+	}, []string{"type"})
+
+	m.registry.MustRegister(
+		m.errorsTotal,
+		// Derived values are computed when scraped
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "active_clients",
+			Help: "Number of WebSocket clients currently connected.",
+		}, func() float64 { return float64(m.activePlayers.Load() + m.activeSpectators.Load()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "peak_rooms",
+			Help: "Highest number of rooms open at once.",
+		}, func() float64 { return float64(m.peakRooms.Load()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "peak_clients",
+			Help: "Highest number of WebSocket clients connected at once.",
+		}, func() float64 { return float64(m.peakClients.Load()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "uptime_seconds",
+			Help: "Seconds since the server started.",
+		}, func() float64 { return time.Since(startedAt).Seconds() }),
+	)
+	return m
+}
+
+func (m *Metrics) newCounter(name, help string) prometheus.Counter {
+	c := prometheus.NewCounter(prometheus.CounterOpts{Name: name, Help: help})
+	m.registry.MustRegister(c)
+	return c
+}
+
+func (m *Metrics) newGauge(name, help string) prometheus.Gauge {
+	g := prometheus.NewGauge(prometheus.GaugeOpts{Name: name, Help: help})
+	m.registry.MustRegister(g)
+	return g
+}
+
+// handlePrometheusMetrics exposes the same counters as handleMetrics and
+// Stats in the Prometheus text format so they can be scraped without a JSON
+// exporter.
+func (s *Server) handlePrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	promhttp.HandlerFor(s.metrics.registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+)
+
+// joinRequest asks the room to register a player connection. ready is closed
+// by room.run() once the connection holds a slot; until then it is waiting in
+// the join queue.
+type joinRequest struct {
+	conn  *websocket.Conn
+	ready chan struct{}
+}
+
+// hasFreeSlot reports whether a new player can be registered right away.
+// There is none while the room is locked.
+func (r *Room) hasFreeSlot() bool {
+	return !r.locked && len(r.clients)+len(r.reserved) < r.maxClients
+}
+
+// enqueue adds a connection to the join queue, ejecting the oldest waiter if
+// the queue is already at Config.MaxQueueDepth. Locked rooms queue players
+// even if the queue is disabled.
+func (r *Room) enqueue(req joinRequest) {
+	if depth := r.server.config.MaxQueueDepth; depth > 0 && len(r.waitingQueue) >= depth {
+		oldest := r.waitingQueue[0]
+		r.waitingQueue = r.waitingQueue[1:]
+		slog.Info("Join queue full, ejecting oldest waiter", slog.String("room", r.id), remoteAttr(oldest.conn))
+		r.writeJSON(oldest.conn, map[string]interface{}{
+			"type": "queueEjected",
+		})
+		oldest.conn.Close()
+		r.notifyQueuePositions()
+	}
+
+	r.waitingQueue = append(r.waitingQueue, req)
+	slog.Info("No free slot, client queued", slog.String("room", r.id), remoteAttr(req.conn), slog.Int("position", len(r.waitingQueue)))
+	if err := r.writeJSON(req.conn, map[string]interface{}{
+		"type":     "queued",
+		"position": len(r.waitingQueue),
+	}); err != nil {
+		slog.Error("Error sending queued message", slog.String("room", r.id), remoteAttr(req.conn), slog.Any("error", err))
+	}
+}
+
+// dequeue registers waiting connections while there are free slots.
+func (r *Room) dequeue() {
+	if len(r.waitingQueue) == 0 || !r.hasFreeSlot() {
+		return
+	}
+
+	for len(r.waitingQueue) > 0 && r.hasFreeSlot() {
+		next := r.waitingQueue[0]
+		r.waitingQueue = r.waitingQueue[1:]
+		if err := r.writeJSON(next.conn, map[string]interface{}{
+			"type": "slotAvailable",
+		}); err != nil {
+			slog.Error("Error sending slotAvailable message", slog.String("room", r.id), remoteAttr(next.conn), slog.Any("error", err))
+		}
+		r.handleRegister(next)
+	}
+	r.notifyQueuePositions()
+}
+
+// removeWaiter takes conn out of the join queue and reports whether it was
+// queued.
+func (r *Room) removeWaiter(conn *websocket.Conn) (joinRequest, bool) {
+	for i, waiter := range r.waitingQueue {
+		if waiter.conn == conn {
+			r.waitingQueue = append(r.waitingQueue[:i], r.waitingQueue[i+1:]...)
+			r.notifyQueuePositions()
+			return waiter, true
+		}
+	}
+	return joinRequest{}, false
+}
+
+// isWaiting reports whether conn is in the join queue.
+func (r *Room) isWaiting(conn *websocket.Conn) bool {
+	for _, waiter := range r.waitingQueue {
+		if waiter.conn == conn {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyQueuePositions tells every waiting connection its current position.
+func (r *Room) notifyQueuePositions() {
+	for i, waiter := range r.waitingQueue {
+		if err := r.writeJSON(waiter.conn, map[string]interface{}{
+			"type":     "queued",
+			"position": i + 1,
+		}); err != nil {
+			slog.Error("Error sending queued message", slog.String("room", r.id), remoteAttr(waiter.conn), slog.Any("error", err))
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// rateLimiter is a token bucket that refills completely once per second.
+// It belongs to a single connection's reader goroutine and is not safe for
+// concurrent use.
+type rateLimiter struct {
+	limit   int
+	tokens  int
+	resetAt time.Time
+}
+
+func newRateLimiter(limit int) *rateLimiter {
+	return &rateLimiter{
+		limit:   limit,
+		tokens:  limit,
+		resetAt: time.Now().Add(time.Second),
+	}
+}
+
+// Allow takes a token from the bucket and reports whether one was available.
+// A limit of zero or less disables rate limiting.
+func (l *rateLimiter) Allow() bool {
+	if l.limit <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	if !now.Before(l.resetAt) {
+		l.tokens = l.limit
+		l.resetAt = now.Add(time.Second)
+	}
+
+	if l.tokens == 0 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// RetryAfter returns the number of whole seconds until the bucket refills.
+func (l *rateLimiter) RetryAfter() int {
+	return int(math.Ceil(time.Until(l.resetAt).Seconds()))
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+// maxReports is how many reports the server keeps for GET /admin/reports.
+// Older ones are dropped but remain in the server log.
+const maxReports = 1000
+
+// Report is a player's report of abusive behavior, e.g. a disruptive peer
+// or an offensive category.
+type Report struct {
+	Time     time.Time `json:"time"`
+	RoomID   string    `json:"roomId"`
+	Reporter string    `json:"reporter"`
+	Target   string    `json:"target,omitempty"`
+	Category string    `json:"category,omitempty"`
+	Reason   string    `json:"reason"`
+}
+
+// submitReport records a player's report for moderators. Reports are not
+// broadcast; only the reporter gets a confirmation. It runs in the
+// connection's reader goroutine.
+func (r *Room) submitReport(conn *websocket.Conn, msg map[string]interface{}) {
+	reporter := r.clientID(conn)
+	if reporter == "" {
+		return
+	}
+	reason, _ := msg["reason"].(string)
+	if strings.TrimSpace(reason) == "" {
+		r.rejectMessage(conn, &InvalidMessageError{Field: "reason", Reason: "empty"})
+		return
+	}
+	if utf8.RuneCountInString(reason) > r.server.config.MaxChatLength {
+		r.rejectMessage(conn, &InvalidMessageError{Field: "reason", Reason: "tooLong"})
+		return
+	}
+
+	report := Report{Time: time.Now(), RoomID: r.id, Reporter: reporter, Reason: reason}
+	report.Target, _ = msg["targetClientId"].(string)
+	report.Category, _ = msg["category"].(string)
+	r.server.addReport(report)
+
+	r.connLogger(conn).Info("Player report", slog.String("room", r.id), slog.String("reporter", reporter), slog.String("target", report.Target), slog.String("category", report.Category), slog.String("reason", reason))
+	r.logEvent("report", map[string]interface{}{
+		"clientId": reporter,
+		"target":   report.Target,
+		"category": report.Category,
+		"reason":   reason,
+	})
+	if err := r.writeJSON(conn, map[string]interface{}{
+		"type": "reportReceived",
+	}); err != nil {
+		slog.Error("Error sending reportReceived message", slog.String("room", r.id), slog.String("client", reporter), slog.Any("error", err))
+	}
+}
+
+// addReport keeps a report, dropping the oldest once maxReports are kept.
+func (s *Server) addReport(report Report) {
+	s.reportsMu.Lock()
+	defer s.reportsMu.Unlock()
+	if len(s.reports) >= maxReports {
+		s.reports = s.reports[1:]
+	}
+	s.reports = append(s.reports, report)
+}
+
+// Reports returns the kept reports, oldest first.
+func (s *Server) Reports() []Report {
+	s.reportsMu.Lock()
+	defer s.reportsMu.Unlock()
+	return append([]Report{}, s.reports...)
+}
+
+func (s *Server) handleGetReports(w http.ResponseWriter, r *http.Request) {
+	reports := s.Reports()
+	if roomID := r.URL.Query().Get("room"); roomID != "" {
+		filtered := reports[:0]
+		for _, report := range reports {
+			if report.RoomID == roomID {
+				filtered = append(filtered, report)
+			}
+		}
+		reports = filtered
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reports)
+}
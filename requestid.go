@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+)
+
+// newRequestID returns a random (version 4) UUID identifying a single
+// WebSocket connection in the logs.
+func newRequestID() string {
+	b := []byte(randomHex(16))
+	// Set the version and variant nibbles
+	b[12] = '4'
+	b[16] = "89ab"[b[16]%4]
+	return fmt.Sprintf("%s-%s-%s-%s-%s", b[0:8], b[8:12], b[12:16], b[16:20], b[20:32])
+}
+
+// setRequestID remembers the request ID of a connection so log lines written
+// by room.run() can be correlated with the connection's own.
+func (r *Room) setRequestID(conn *websocket.Conn, requestID string) {
+	r.connsMu.Lock()
+	r.requestIDs[conn] = requestID
+	r.connsMu.Unlock()
+}
+
+// forgetRequestID drops the request ID once the room is done with conn.
+func (r *Room) forgetRequestID(conn *websocket.Conn) {
+	r.connsMu.Lock()
+	delete(r.requestIDs, conn)
+	r.connsMu.Unlock()
+}
+
+// requestID returns the request ID of conn, or an empty string.
+func (r *Room) requestID(conn *websocket.Conn) string {
+	r.connsMu.Lock()
+	defer r.connsMu.Unlock()
+	return r.requestIDs[conn]
+}
+
+// connLogger returns a logger that tags every line with the request ID of
+// conn.
+func (r *Room) connLogger(conn *websocket.Conn) *slog.Logger {
+	if requestID := r.requestID(conn); requestID != "" {
+		return slog.With(slog.String("requestId", requestID))
+	}
+	return slog.Default()
+}
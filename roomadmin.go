@@ -0,0 +1,266 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	ErrRoomNotFound   = errors.New("room not found")
+	ErrClientNotFound = errors.New("client not found")
+)
+
+// drainQuietPeriod is how long a closed room keeps consuming its channels
+// after the last send, so reader goroutines never block on a dead room.
+const drainQuietPeriod = 2 * time.Second
+
+// kickRequest asks room.run() to disconnect a player, given either by its
+// connection or by its client ID. The outcome is sent on result.
+type kickRequest struct {
+	conn     *websocket.Conn
+	clientID string
+	reason   string
+	result   chan error
+}
+
+// CloseRoom disconnects everyone in the room and removes it from the server.
+func (s *Server) CloseRoom(id string) error {
+	room, ok := s.GetRoom(id)
+	if !ok || !s.removeRoom(room, "admin") {
+		return ErrRoomNotFound
+	}
+	slog.Info("Closed room", slog.String("room", id), slog.Duration("age", time.Since(room.createdAt)))
+	return nil
+}
+
+// removeRoom removes room from the server and closes it with reason. It
+// reports false if the room was already removed, e.g. by a concurrent
+// cleanup. It never blocks, so room.run() may call it too.
+func (s *Server) removeRoom(room *Room, reason string) bool {
+	if !s.rooms.CompareAndDelete(room.id, room) {
+		return false
+	}
+	room.Close(reason)
+	if s.broker != nil {
+		s.broker.Unsubscribe(room.id)
+	}
+	s.metrics.activeRooms.Add(-1)
+	s.notifyWebhook("roomClosed", room.id)
+	if s.store != nil {
+		if err := s.store.DeactivateRoom(room.id); err != nil {
+			slog.Error("Error deactivating persisted room", slog.String("room", room.id), slog.Any("error", err))
+		}
+	}
+	return true
+}
+
+// KickClient disconnects a single player. The slot is not reserved, so the
+// player cannot come back with its session token.
+func (s *Server) KickClient(roomID, clientID string) error {
+	room, ok := s.GetRoom(roomID)
+	if !ok {
+		return ErrRoomNotFound
+	}
+
+	return room.requestKick(kickRequest{clientID: clientID, reason: "admin"})
+}
+
+// Kick disconnects a player with a policy violation close frame carrying
+// reason. A non-empty reason is also sent as a "kicked" message first. Like
+// KickClient it does not reserve the slot. It may be called from any
+// goroutine; the kick is carried out by room.run().
+func (r *Room) Kick(conn *websocket.Conn, reason string) error {
+	return r.requestKick(kickRequest{conn: conn, reason: reason})
+}
+
+// requestKick hands a kick to room.run() and waits for the outcome.
+func (r *Room) requestKick(req kickRequest) error {
+	req.result = make(chan error, 1)
+	select {
+	case r.kick <- req:
+	case <-r.ctx.Done():
+		return ErrRoomNotFound
+	}
+	return <-req.result
+}
+
+// handleKick runs in room.run().
+func (r *Room) handleKick(req kickRequest) {
+	conn := req.conn
+	if conn == nil {
+		for client, clientID := range r.clientIDs {
+			if clientID == req.clientID {
+				conn = client
+				break
+			}
+		}
+	}
+	if !r.clients[conn] {
+		req.result <- ErrClientNotFound
+		return
+	}
+
+	clientID := r.clientIDs[conn]
+	delete(r.sessions, conn)
+	r.forgetName(clientID)
+	if req.reason != "" {
+		if err := r.writeJSON(conn, map[string]interface{}{
+			"type":   "kicked",
+			"reason": req.reason,
+		}); err != nil {
+			slog.Error("Error sending kicked message", slog.String("room", r.id), slog.String("client", clientID), slog.Any("error", err))
+		}
+	}
+	conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.ClosePolicyViolation, req.reason),
+		time.Now().Add(time.Second),
+	)
+	slog.Info("Kicked client", slog.String("room", r.id), slog.String("client", clientID), slog.String("reason", req.reason))
+	r.handleUnregister(conn)
+	req.result <- nil
+}
+
+// Close shuts the room down: room.run() delivers what is already queued,
+// sends "serverClose" with reason to every connection and closes them. It
+// never blocks and is safe to call more than once.
+func (r *Room) Close(reason string) {
+	select {
+	case r.closing <- reason:
+	case <-r.ctx.Done():
+	default:
+		// A close is already pending
+	}
+}
+
+// handleClose tells every connection why the room is closing and closes it.
+// It runs in room.run(), which returns afterwards.
+func (r *Room) handleClose(reason string) {
+	r.flushBroadcasts()
+	r.stopTimers()
+
+	code := websocket.CloseNormalClosure
+	select {
+	case <-r.server.shutdown:
+		// Clients may come back once the server has restarted
+		code = websocket.CloseGoingAway
+	default:
+	}
+
+	conns := make([]*websocket.Conn, 0, len(r.clients)+len(r.spectators)+len(r.waitingQueue))
+	for client := range r.clients {
+		conns = append(conns, client)
+	}
+	for spectator := range r.spectators {
+		conns = append(conns, spectator)
+	}
+	for _, waiter := range r.waitingQueue {
+		conns = append(conns, waiter.conn)
+	}
+	for _, conn := range conns {
+		if err := r.writeJSON(conn, map[string]interface{}{
+			"type":   "serverClose",
+			"reason": reason,
+		}); err != nil {
+			slog.Error("Error sending serverClose message", slog.String("room", r.id), remoteAttr(conn), slog.Any("error", err))
+		}
+		conn.WriteControl(
+			websocket.CloseMessage,
+			websocket.FormatCloseMessage(code, reason),
+			time.Now().Add(time.Second),
+		)
+		conn.Close()
+	}
+	r.cancel()
+	r.mu.Lock()
+	r.notifyEmptyLocked()
+	r.mu.Unlock()
+
+	r.server.metrics.activePlayers.Add(-int64(len(r.clients)))
+	r.server.metrics.activeSpectators.Add(-int64(len(r.spectators)))
+	r.drain()
+}
+
+// stopTimers stops every timer of the room, so none of them fires into the
+// closed room. It runs in room.run().
+func (r *Room) stopTimers() {
+	r.mu.Lock()
+	r.stopRoundTimerLocked()
+	for target := range r.kickVotes {
+		r.clearKickVoteLocked(target)
+	}
+	for clientID := range r.typing {
+		r.forgetTypingLocked(clientID)
+	}
+	r.mu.Unlock()
+	r.stopCountdown()
+	r.stopPostGameTimer()
+	if r.lockTimer != nil {
+		r.lockTimer.Stop()
+		r.lockTimer = nil
+	}
+	if r.coalesceTimer != nil {
+		r.coalesceTimer.Stop()
+		r.coalesceFlush = nil
+	}
+	for conn := range r.graceTimers {
+		r.stopGrace(conn)
+	}
+	for _, reservation := range r.reserved {
+		reservation.timer.Stop()
+	}
+}
+
+// flushBroadcasts delivers the messages already queued when the room is
+// closed, so clients see the final state before "serverClose". Game actions
+// are dropped.
+func (r *Room) flushBroadcasts() {
+	for {
+		select {
+		case broadcastMsg := <-r.broadcast:
+			if !broadcastMsg.control {
+				r.broadcastMessage(broadcastMsg)
+			}
+		default:
+			r.flushCoalesced()
+			return
+		}
+	}
+}
+
+// drain consumes everything reader goroutines still send to the closed room
+// until the channels have been quiet for drainQuietPeriod.
+func (r *Room) drain() {
+	quiet := time.NewTimer(drainQuietPeriod)
+	defer quiet.Stop()
+
+	for {
+		select {
+		case req := <-r.register:
+			req.conn.Close()
+		case conn := <-r.spectate:
+			conn.Close()
+		case req := <-r.reconnect:
+			req.conn.Close()
+		case conn := <-r.unregister:
+			conn.Close()
+		case conn := <-r.disconnect:
+			conn.Close()
+		case req := <-r.kick:
+			req.result <- ErrRoomNotFound
+		case req := <-r.snapshot:
+			r.mu.Lock()
+			req.result <- r.snapshotLocked()
+			r.mu.Unlock()
+		case <-r.broadcast:
+		case <-r.priority:
+		case <-r.roundTimeout:
+		case <-quiet.C:
+			return
+		}
+		quiet.Reset(drainQuietPeriod)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+)
+
+// sendRoomInfo answers a "roomInfo" query with the current room state, sent
+// to the asking connection only. Clients use it to catch up after being in
+// the background. It runs in the reader goroutine and changes nothing.
+func (r *Room) sendRoomInfo(conn *websocket.Conn) {
+	r.mu.Lock()
+	scores := make(map[string]int, len(r.scores))
+	for clientID, total := range r.scores {
+		scores[clientID] = total
+	}
+	payload := map[string]interface{}{
+		"type":              "roomInfo",
+		"round":             r.round,
+		"clientCount":       len(r.clientIDs),
+		"usedCategoryCount": len(r.usedCategories),
+		"scores":            scores,
+		"yourClientId":      r.clientIDs[conn],
+	}
+	r.mu.Unlock()
+
+	if err := r.writeJSON(conn, payload); err != nil {
+		slog.Error("Error sending roomInfo message", slog.String("room", r.id), remoteAttr(conn), slog.Any("error", err))
+	}
+}
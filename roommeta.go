@@ -0,0 +1,87 @@
+package main
+
+import (
+	"log/slog"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	maxRoomNameLength        = 64
+	maxRoomDescriptionLength = 256
+)
+
+// truncateRunes shortens s to at most n characters and reports whether it
+// had to.
+func truncateRunes(s string, n int) (string, bool) {
+	if utf8.RuneCountInString(s) <= n {
+		return s, false
+	}
+	return string([]rune(s)[:n]), true
+}
+
+// normalizeRoomMeta applies the length limits to a room name and description
+// and returns the names of the fields that were truncated.
+func normalizeRoomMeta(name, description string) (string, string, []string) {
+	var truncated []string
+	name, cut := truncateRunes(name, maxRoomNameLength)
+	if cut {
+		truncated = append(truncated, "name")
+	}
+	description, cut = truncateRunes(description, maxRoomDescriptionLength)
+	if cut {
+		truncated = append(truncated, "description")
+	}
+	return name, description, truncated
+}
+
+// setRoomMeta updates the room's name and description on behalf of the room
+// creator and announces the change. Fields missing from msg are left as is.
+func (r *Room) setRoomMeta(conn *websocket.Conn, msg map[string]interface{}) {
+	clientID := r.clientID(conn)
+
+	r.mu.Lock()
+	if clientID == "" || clientID != r.creatorID {
+		r.mu.Unlock()
+		if err := r.writeJSON(conn, map[string]interface{}{
+			"type": "error",
+			"code": "notCreator",
+		}); err != nil {
+			slog.Error("Error sending notCreator message", slog.String("room", r.id), slog.String("client", clientID), slog.Any("error", err))
+		}
+		return
+	}
+
+	name, description := r.name, r.description
+	if value, ok := msg["name"].(string); ok {
+		name = value
+	}
+	if value, ok := msg["description"].(string); ok {
+		description = value
+	}
+	name, description, truncated := normalizeRoomMeta(name, description)
+	r.name, r.description = name, description
+	r.mu.Unlock()
+
+	for _, field := range truncated {
+		limit := maxRoomNameLength
+		if field == "description" {
+			limit = maxRoomDescriptionLength
+		}
+		if err := r.writeJSON(conn, map[string]interface{}{
+			"type":  "warning",
+			"code":  "truncated",
+			"field": field,
+			"limit": limit,
+		}); err != nil {
+			slog.Error("Error sending truncation warning", slog.String("room", r.id), slog.String("client", clientID), slog.Any("error", err))
+		}
+	}
+
+	r.broadcastJSON(conn, map[string]interface{}{
+		"type":        "roomMeta",
+		"name":        name,
+		"description": description,
+	})
+}
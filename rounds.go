@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newBroadcast encodes payload into a BroadcastMessage. The payload's "type"
+// field is used as the message type. The message is server-generated, so it
+// reaches the sender too.
+func newBroadcast(sender *websocket.Conn, payload map[string]interface{}) (BroadcastMessage, error) {
+	message, err := json.Marshal(payload)
+	if err != nil {
+		return BroadcastMessage{}, err
+	}
+	msgType, _ := payload["type"].(string)
+	return BroadcastMessage{
+		message:       message,
+		sender:        sender,
+		msgType:       MessageType(msgType),
+		includeSender: true,
+	}, nil
+}
+
+// broadcastJSON encodes payload and queues it for broadcast to the room. It
+// must not be called from room.run().
+func (r *Room) broadcastJSON(sender *websocket.Conn, payload map[string]interface{}) {
+	broadcastMsg, err := newBroadcast(sender, payload)
+	if err != nil {
+		slog.Error("Error marshalling broadcast message", slog.String("room", r.id), slog.Any("type", payload["type"]), slog.Any("error", err))
+		return
+	}
+	broadcastMsg.timestamp = time.Now()
+	enqueue(r, r.broadcast, broadcastMsg)
+}
+
+// gameOverLocked reports whether Config.MaxRounds rounds have been played.
+// r.mu must be held.
+func (r *Room) gameOverLocked() bool {
+	return r.server.config.MaxRounds > 0 && r.round > r.server.config.MaxRounds
+}
+
+// startRoundTimerLocked (re)starts the timer for the current round. When it
+// fires the round is ended from room.run(). r.mu must be held.
+func (r *Room) startRoundTimerLocked() {
+	if r.roundTimer != nil {
+		r.roundTimer.Stop()
+		r.roundTimer = nil
+	}
+	if r.server.config.RoundDuration <= 0 || r.gameOverLocked() {
+		return
+	}
+
+	round := r.round
+	r.roundTimer = time.AfterFunc(r.server.config.RoundDuration, func() {
+		select {
+		case r.roundTimeout <- round:
+		case <-r.ctx.Done():
+		}
+	})
+}
+
+// stopRoundTimerLocked cancels a pending round timer. r.mu must be held.
+func (r *Room) stopRoundTimerLocked() {
+	if r.roundTimer != nil {
+		r.roundTimer.Stop()
+		r.roundTimer = nil
+	}
+}
+
+// finishRound closes the current round and returns the messages announcing
+// it: the round's scores and, after the last round, the end of the game.
+func (r *Room) finishRound() []map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stopRoundTimerLocked()
+	round := r.round
+	scores := make(map[string]int, len(r.roundScores))
+	for clientID, points := range r.roundScores {
+		scores[clientID] = points
+	}
+	r.round++
+	r.logEventLocked("roundEnded", map[string]interface{}{
+		"round":  round,
+		"scores": scores,
+	})
+
+	payloads := []map[string]interface{}{{
+		"type":   "roundEnd",
+		"round":  round,
+		"scores": scores,
+		"names":  r.namesLocked(),
+	}}
+	if r.gameOverLocked() {
+		payloads = append(payloads, map[string]interface{}{
+			"type":   "gameOver",
+			"rounds": round,
+		})
+		payloads = append(payloads, r.endGameLocked(round)...)
+	}
+	return payloads
+}
+
+// beginRound resets the per-round state and returns the message announcing
+// the new round. It reports false once the game is over.
+func (r *Room) beginRound() (map[string]interface{}, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.gameOverLocked() {
+		return nil, false
+	}
+	r.roundScores = make(map[string]int)
+	r.revealed = make(map[string]bool)
+	// Pick up categories reloaded during the previous round
+	if categories, ok := r.server.getCategoryPack(r.pack); ok {
+		r.categories = categories
+	}
+	r.startRoundTimerLocked()
+
+	return map[string]interface{}{
+		"type":  "roundStart",
+		"round": r.round,
+	}, true
+}
+
+// startRound restarts the current round on behalf of sender: its scores,
+// reveals and clock start over. Rounds advance on their own, see
+// advanceRound.
+func (r *Room) startRound(sender *websocket.Conn) {
+	if payload, ok := r.beginRound(); ok {
+		r.broadcastJSON(sender, payload)
+	}
+}
+
+// advanceRound announces lead, ends the current round and starts the next
+// one. Both happen within the same room.run() iteration, so nobody can join
+// in between and the room needs no lock. It runs in room.run().
+func (r *Room) advanceRound(sender *websocket.Conn, lead map[string]interface{}) {
+	payloads := []map[string]interface{}{lead}
+	payloads = append(payloads, r.finishRound()...)
+	r.persistState()
+	if payload, ok := r.beginRound(); ok {
+		payloads = append(payloads, payload)
+	}
+	r.broadcastPayloads(sender, payloads)
+}
+
+// handleRoundTimeout ends a round whose timer expired and moves on to the
+// next one. Timeouts for rounds that already ended are ignored.
+func (r *Room) handleRoundTimeout(round int) {
+	r.mu.Lock()
+	stale := round != r.round
+	r.mu.Unlock()
+	if stale {
+		return
+	}
+
+	slog.Info("Round timed out", slog.String("room", r.id), slog.Int("round", round))
+	r.advanceRound(nil, map[string]interface{}{
+		"type":  "roundTimeout",
+		"round": round,
+	})
+}
+
+// handleControl applies a game action queued by a reader goroutine. It runs
+// in room.run().
+func (r *Room) handleControl(broadcastMsg BroadcastMessage) {
+	switch broadcastMsg.msgType {
+	case TypeReveal:
+		r.handleReveal(broadcastMsg.sender)
+	case TypeResetGame:
+		r.handleResetGame(broadcastMsg.sender)
+	case TypeLockRoom:
+		r.lockRoom()
+	case TypeUnlockRoom:
+		r.unlockRoom()
+	case TypeSetAllowedTypes:
+		r.handleSetAllowedTypes(broadcastMsg)
+	case TypeGameEnd:
+		r.handleGameEnd(broadcastMsg.sender)
+	case TypeCancelCountdown:
+		if r.clients[broadcastMsg.sender] {
+			r.cancelCountdown(broadcastMsg.sender, "host")
+		}
+	case TypePostGameTimeout:
+		r.handlePostGameTimeout()
+	default:
+		slog.Warn("Ignoring unknown control message", slog.String("room", r.id), slog.String("type", string(broadcastMsg.msgType)))
+	}
+}
+
+// handleReveal records a player's reveal. Revealing again counts once. Once
+// every player revealed, the next round starts. It runs in room.run().
+func (r *Room) handleReveal(conn *websocket.Conn) {
+	if !r.clients[conn] {
+		return
+	}
+	r.mu.Lock()
+	r.revealed[r.clientIDs[conn]] = true
+	allRevealed := true
+	for _, clientID := range r.clientIDs {
+		if !r.revealed[clientID] {
+			allRevealed = false
+			break
+		}
+	}
+	if allRevealed {
+		r.revealed = make(map[string]bool)
+	}
+	r.mu.Unlock()
+	if !allRevealed {
+		return
+	}
+
+	r.advanceRound(conn, map[string]interface{}{
+		"type": "allRevealed",
+	})
+}
+
+// handleResetGame clears the used categories, scores and round so the room
+// can play a new game without reconnecting. It runs in room.run().
+func (r *Room) handleResetGame(conn *websocket.Conn) {
+	if !r.clients[conn] {
+		return
+	}
+	r.mu.Lock()
+	r.stopRoundTimerLocked()
+	r.usedCategories = make([]string, 0)
+	r.scores = make(map[string]int)
+	r.roundScores = make(map[string]int)
+	r.skipVotes = make(map[string]bool)
+	r.round = 1
+	r.revealed = make(map[string]bool)
+	// A reset mid-game keeps playing; after the game ended the room waits
+	// for players again, or starts right away if it is still full. A
+	// paused game is dropped and the refill starts a new one
+	if r.state == StateEnded {
+		r.transitionLocked(StateWaiting)
+	}
+	r.paused = false
+	if len(r.clients) == r.maxClients {
+		r.transitionLocked(StatePlaying)
+		r.startRoundTimerLocked()
+	}
+	r.logEventLocked("gameReset", map[string]interface{}{
+		"clientId": r.clientIDs[conn],
+	})
+	r.mu.Unlock()
+	// Late joiners should not replay the previous game
+	r.history = nil
+	r.stopPostGameTimer()
+	r.unlockRoom()
+
+	slog.Info("Game reset", slog.String("room", r.id), slog.String("client", r.clientIDs[conn]))
+	r.persistState()
+	r.broadcastPayloads(conn, []map[string]interface{}{{
+		"type": "gameReset",
+	}})
+}
+
+// broadcastPayloads encodes and delivers messages to the room. It runs in
+// room.run().
+func (r *Room) broadcastPayloads(sender *websocket.Conn, payloads []map[string]interface{}) {
+	for _, payload := range payloads {
+		broadcastMsg, err := newBroadcast(sender, payload)
+		if err != nil {
+			slog.Error("Error marshalling broadcast message", slog.String("room", r.id), slog.Any("type", payload["type"]), slog.Any("error", err))
+			continue
+		}
+		r.broadcastMessage(broadcastMsg)
+	}
+}
+
+// announcePeerLeft tells the remaining players who left, the current round
+// and scores. A game in progress is paused: the room waits for players again
+// until it is refilled, see announceIfFull. It runs in room.run().
+func (r *Room) announcePeerLeft(clientID string) {
+	if len(r.clients) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	if r.transitionLocked(StateWaiting) {
+		r.paused = true
+	}
+	waiting := r.state == StateWaiting
+	round := r.round
+	scores := make(map[string]int, len(r.scores))
+	for id, total := range r.scores {
+		scores[id] = total
+	}
+	names := r.namesLocked()
+	r.mu.Unlock()
+
+	payloads := []map[string]interface{}{{
+		"type":     "peerLeft",
+		"clientId": clientID,
+		"round":    round,
+		"scores":   scores,
+		"names":    names,
+	}}
+	if waiting {
+		payloads = append(payloads, map[string]interface{}{
+			"type":         "waitingForPlayers",
+			"currentRound": round,
+		})
+	}
+	r.broadcastPayloads(nil, payloads)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// A game that loses a player waits for players again and rejects game
+// messages until the room is refilled, which resumes it.
+func TestPeerLeftPausesGame(t *testing.T) {
+	s, url := newTestServer(t, func(config *Config) {
+		config.DisconnectGrace = 0
+		config.ReconnectWindow = 0
+	})
+	room, _, err := s.getOrCreateRoom("pause", RoomOptions{MaxClients: 2})
+	if err != nil {
+		t.Fatalf("getOrCreateRoom: %v", err)
+	}
+
+	host := dial(t, url, "room=pause")
+	expect(t, host, "welcome")
+	leaves := dial(t, url, "room=pause")
+	expect(t, leaves, "welcome")
+	expect(t, host, "gameStart")
+	leaves.Close()
+
+	expect(t, host, "waitingForPlayers")
+	if state := room.State(); state != StateWaiting {
+		t.Fatalf("state after a player left = %s, want waiting", state)
+	}
+	send(t, host, map[string]interface{}{"type": "newCategory"})
+	if msg := expect(t, host, "invalidState"); msg["current"] != "waiting" {
+		t.Errorf("invalidState current = %v, want waiting", msg["current"])
+	}
+
+	refill := dial(t, url, "room=pause")
+	expect(t, refill, "welcome")
+	expect(t, host, "roomFull")
+	if state := room.State(); state != StatePlaying {
+		t.Fatalf("state after the refill = %s, want playing", state)
+	}
+
+	// The game resumes instead of starting over
+	send(t, host, map[string]interface{}{"type": "newCategory"})
+	host.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		for _, msg := range readMessages(t, host, "newCategory") {
+			switch msg["type"] {
+			case "gameStart":
+				t.Fatal("refilling a paused game sent gameStart")
+			case "newCategory":
+				return
+			}
+		}
+	}
+}
+
+// Once every player revealed, the next round starts without the host
+// sending newRound.
+func TestRevealStartsNextRound(t *testing.T) {
+	s, url := newTestServer(t, nil)
+	if _, _, err := s.getOrCreateRoom("reveal", RoomOptions{MaxClients: 2}); err != nil {
+		t.Fatalf("getOrCreateRoom: %v", err)
+	}
+	first := dial(t, url, "room=reveal")
+	expect(t, first, "welcome")
+	second := dial(t, url, "room=reveal")
+	expect(t, second, "welcome")
+	expect(t, first, "gameStart")
+
+	send(t, first, map[string]interface{}{"type": "reveal"})
+	send(t, second, map[string]interface{}{"type": "reveal"})
+	expect(t, first, "allRevealed")
+	if msg := expect(t, first, "roundEnd"); msg["round"] != float64(1) {
+		t.Errorf("roundEnd round = %v, want 1", msg["round"])
+	}
+	if msg := expect(t, first, "roundStart"); msg["round"] != float64(2) {
+		t.Errorf("roundStart round = %v, want 2", msg["round"])
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+)
+
+// schemaField is a required field of a client message and the JSON kind its
+// value must have.
+type schemaField struct {
+	name string
+	kind string
+}
+
+// messageSchema lists the required fields per client message type. Types
+// without an entry only need a "type".
+var messageSchema = map[MessageType][]schemaField{
+	TypeChat:      {{"text", "string"}},
+	TypeKickVote:  {{"targetClientId", "string"}},
+	TypePong:      {{"serverTime", "number"}},
+	TypeReconnect: {{"token", "string"}},
+	TypeReport:    {{"reason", "string"}},
+	TypeScore:     {{"points", "number"}},
+	TypeSetName:   {{"name", "string"}},
+	TypeTyping:    {{"isTyping", "boolean"}},
+}
+
+// jsonKind names the JSON kind of a value decoded by encoding/json.
+func jsonKind(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return "null"
+	}
+}
+
+// validateMessage checks msg against messageSchema and returns an
+// InvalidMessageError for the first offending field.
+func validateMessage(msg map[string]interface{}) *InvalidMessageError {
+	value, present := msg["type"]
+	if !present {
+		return &InvalidMessageError{Field: "type", Reason: "required"}
+	}
+	msgType, isString := value.(string)
+	if !isString {
+		return &InvalidMessageError{Field: "type", Reason: "invalidType"}
+	}
+
+	for _, f := range messageSchema[MessageType(msgType)] {
+		value, present := msg[f.name]
+		if !present || value == nil {
+			return &InvalidMessageError{Field: f.name, Reason: "required"}
+		}
+		if jsonKind(value) != f.kind {
+			return &InvalidMessageError{Field: f.name, Reason: "invalidType"}
+		}
+	}
+	return nil
+}
+
+// rejectMessage tells the sender why its message was dropped.
+func (r *Room) rejectMessage(conn *websocket.Conn, invalid *InvalidMessageError) {
+	slog.Warn("Rejected invalid message", slog.String("room", r.id), remoteAttr(conn), slog.String("field", invalid.Field), slog.String("reason", invalid.Reason))
+	r.logEvent("messageError", map[string]interface{}{
+		"clientId": r.clientID(conn),
+		"field":    invalid.Field,
+		"reason":   invalid.Reason,
+	})
+	if err := r.writeJSON(conn, map[string]interface{}{
+		"type":   "validationError",
+		"field":  invalid.Field,
+		"reason": invalid.Reason,
+	}); err != nil {
+		slog.Error("Error sending validationError message", slog.String("room", r.id), remoteAttr(conn), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/gorilla/websocket"
+)
+
+// clientID returns the ID assigned to conn, or an empty string if the
+// connection is not a registered client.
+func (r *Room) clientID(conn *websocket.Conn) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.clientIDs[conn]
+}
+
+// submitScore validates and records points for the sending client, then
+// broadcasts the updated scoreboard. Invalid submissions are only reported
+// back to the sender.
+func (r *Room) submitScore(conn *websocket.Conn, value interface{}) {
+	clientID := r.clientID(conn)
+	if clientID == "" {
+		return
+	}
+
+	maxPoints := r.server.config.MaxPointsPerRound
+	points, ok := value.(float64)
+	if !ok || points != math.Trunc(points) {
+		r.rejectScore(conn, clientID, "points must be a whole number")
+		return
+	}
+	if points < 0 {
+		r.rejectScore(conn, clientID, "points must not be negative")
+		return
+	}
+
+	r.mu.Lock()
+	if r.roundScores[clientID]+int(points) > maxPoints {
+		r.mu.Unlock()
+		r.rejectScore(conn, clientID, fmt.Sprintf("at most %d points can be scored per round", maxPoints))
+		return
+	}
+	r.roundScores[clientID] += int(points)
+	r.scores[clientID] += int(points)
+	scores := make(map[string]int, len(r.scores))
+	for id, total := range r.scores {
+		scores[id] = total
+	}
+	names := r.namesLocked()
+	r.mu.Unlock()
+
+	r.broadcastJSON(conn, map[string]interface{}{
+		"type":   "scoreUpdate",
+		"scores": scores,
+		"names":  names,
+	})
+}
+
+func (r *Room) rejectScore(conn *websocket.Conn, clientID, reason string) {
+	slog.Warn("Rejected score", slog.String("room", r.id), slog.String("client", clientID), slog.String("reason", reason))
+	r.logEvent("messageError", map[string]interface{}{
+		"clientId": clientID,
+		"msgType":  "score",
+		"reason":   reason,
+	})
+	err := r.writeJSON(conn, map[string]interface{}{
+		"type":   "error",
+		"code":   "invalidScore",
+		"reason": reason,
+	})
+	if err != nil {
+		slog.Error("Error sending score rejection", slog.String("room", r.id), slog.String("client", clientID), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RoomFilter selects rooms for SearchRooms. Zero values match every room.
+type RoomFilter struct {
+	// Pack only matches rooms using this category pack.
+	Pack string
+	// Available only matches rooms with at least one open player slot.
+	Available bool
+	// Name matches rooms whose name contains it, ignoring case.
+	Name string
+	// Limit caps the number of results; zero means no limit.
+	Limit int
+	// Offset skips that many matching rooms.
+	Offset int
+}
+
+// matches reports whether a room passes the filter.
+func (f RoomFilter) matches(info RoomInfo) bool {
+	if f.Pack != "" && info.Pack != f.Pack {
+		return false
+	}
+	if f.Available && info.PlayerCount >= info.MaxClients {
+		return false
+	}
+	if f.Name != "" && !strings.Contains(strings.ToLower(info.Name), strings.ToLower(f.Name)) {
+		return false
+	}
+	return true
+}
+
+// SearchRooms returns the active rooms matching filter, oldest first.
+func (s *Server) SearchRooms(filter RoomFilter) []RoomInfo {
+	infos := s.roomInfos()
+	rooms := make([]RoomInfo, 0, len(infos))
+	for _, info := range infos {
+		if filter.matches(info) {
+			rooms = append(rooms, info)
+		}
+	}
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].CreatedAt.Before(rooms[j].CreatedAt)
+	})
+
+	if filter.Offset >= len(rooms) {
+		return []RoomInfo{}
+	}
+	rooms = rooms[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(rooms) {
+		rooms = rooms[:filter.Limit]
+	}
+	return rooms
+}
+
+// parseRoomFilter reads a RoomFilter from the query string of GET /rooms.
+func parseRoomFilter(r *http.Request) (RoomFilter, error) {
+	query := r.URL.Query()
+	filter := RoomFilter{
+		Pack: query.Get("pack"),
+		Name: query.Get("name"),
+	}
+
+	var err error
+	if value := query.Get("available"); value != "" {
+		if filter.Available, err = strconv.ParseBool(value); err != nil {
+			return RoomFilter{}, errors.New("available must be true or false")
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 0 {
+			return RoomFilter{}, errors.New("limit must be a non-negative integer")
+		}
+	}
+	if value := query.Get("offset"); value != "" {
+		if filter.Offset, err = strconv.Atoi(value); err != nil || filter.Offset < 0 {
+			return RoomFilter{}, errors.New("offset must be a non-negative integer")
+		}
+	}
+	return filter, nil
+}
+
+func (s *Server) handleListRooms(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseRoomFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.SearchRooms(filter))
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// reconnectRequest asks the room to hand a reserved slot back to a
+// returning client.
+type reconnectRequest struct {
+	conn  *websocket.Conn
+	token string
+}
+
+// reservation holds a disconnected client's slot and identity until the
+// reconnect window expires.
+type reservation struct {
+	clientID string
+	timer    *time.Timer
+	// restored is set for slots recreated from the store after a restart
+	restored bool
+}
+
+// randomHex returns n random bytes encoded as a hex string.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		slog.Error("Error generating random ID", slog.Any("error", err))
+		os.Exit(1)
+	}
+	return hex.EncodeToString(b)
+}
+
+// newSessionToken returns a random token identifying a client's slot.
+func newSessionToken() string {
+	return randomHex(16)
+}
+
+// newClientID returns a short random ID that peers can use to tell clients
+// apart.
+func newClientID() string {
+	return randomHex(8)
+}
+
+// frame is a message read from a connection before it is registered.
+type frame struct {
+	frameType int
+	data      []byte
+}
+
+// readFirstFrame reads the first message of a connection, which decides
+// whether it reclaims a reserved slot or authenticates, see
+// handleConnections.
+func readFirstFrame(conn *websocket.Conn, timeout time.Duration) (*frame, error) {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	frameType, data, err := conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	return &frame{frameType: frameType, data: data}, nil
+}
+
+// object returns the frame decoded as a JSON object, or nil if it is not
+// one.
+func (f *frame) object() map[string]interface{} {
+	var msg map[string]interface{}
+	if f.frameType != websocket.TextMessage || json.Unmarshal(f.data, &msg) != nil {
+		return nil
+	}
+	return msg
+}
+
+// reserveSlotLocked keeps a disconnected client's slot for the reconnect
+// window. r.mu must be held.
+func (r *Room) reserveSlotLocked(token, clientID string) {
+	r.reserved[token] = &reservation{
+		clientID: clientID,
+		timer: time.AfterFunc(r.server.config.ReconnectWindow, func() {
+			select {
+			case r.expire <- token:
+			case <-r.ctx.Done():
+			}
+		}),
+	}
+	slog.Info("Reserved slot for reconnect", slog.String("room", r.id), slog.String("client", clientID), slog.Int("reserved", len(r.reserved)))
+}
+
+// releaseSlot frees a reserved slot once the reconnect window has passed.
+func (r *Room) releaseSlot(token string) {
+	r.mu.Lock()
+	reserved, ok := r.reserved[token]
+	if !ok {
+		r.mu.Unlock()
+		return
+	}
+	delete(r.reserved, token)
+	delete(r.clientNames, reserved.clientID)
+	r.mu.Unlock()
+	slog.Info("Reconnect window expired", slog.String("room", r.id), slog.String("client", reserved.clientID), slog.Int("reserved", len(r.reserved)))
+	r.dequeue()
+}
+
+func (r *Room) handleReconnect(req reconnectRequest) {
+	if r.resumeGraced(req) {
+		return
+	}
+
+	reserved, ok := r.reserved[req.token]
+	if !ok {
+		slog.Warn("Reconnect rejected: unknown or expired session token", slog.String("room", r.id), remoteAttr(req.conn))
+		failedMsg, _ := json.Marshal(map[string]interface{}{
+			"type": "reconnectFailed",
+		})
+		r.writeMessage(req.conn, websocket.TextMessage, failedMsg)
+		// Connections that were only admitted to redeem a slot have nowhere
+		// to go; queued ones keep waiting for a free slot
+		_, registered := r.clients[req.conn]
+		if !registered && !r.isWaiting(req.conn) {
+			req.conn.Close()
+		}
+		return
+	}
+
+	reserved.timer.Stop()
+	r.mu.Lock()
+	delete(r.reserved, req.token)
+	r.mu.Unlock()
+	if waiter, ok := r.removeWaiter(req.conn); ok {
+		close(waiter.ready)
+	}
+
+	_, registered := r.clients[req.conn]
+	if !registered {
+		r.clients[req.conn] = true
+		r.server.metrics.activePlayers.Add(1)
+		r.server.metrics.updatePeaks()
+	}
+	r.sessions[req.conn] = req.token
+	r.mu.Lock()
+	tempID := r.clientIDs[req.conn]
+	r.clientIDs[req.conn] = reserved.clientID
+	r.lastActivity = time.Now()
+	r.mu.Unlock()
+	if tempID != "" {
+		r.retireClientID(req.conn, tempID)
+	}
+	r.mode.OnClientJoin(r, req.conn)
+	r.runJoinHook(reserved.clientID)
+	slog.Info("Client reconnected", slog.String("room", r.id), slog.String("client", reserved.clientID), remoteAttr(req.conn), slog.Int("clients", len(r.clients)))
+	r.logEvent("clientReconnected", map[string]interface{}{
+		"clientId": reserved.clientID,
+	})
+
+	if reserved.restored {
+		if err := r.writeJSON(req.conn, map[string]interface{}{
+			"type": "serverRestarted",
+		}); err != nil {
+			slog.Error("Error sending serverRestarted message", slog.String("room", r.id), remoteAttr(req.conn), slog.Any("error", err))
+		}
+	}
+
+	r.sendReconnected(req.conn, req.token, reserved.clientID)
+	r.claimHostIfVacant(req.conn)
+	if !registered {
+		r.announceIfFull()
+	}
+}
+
+// retireClientID forgets the client ID a registered connection joined with
+// once it takes over a reserved or graced slot with a session token. The ID
+// never comes back, so the others are told it left. The slot the connection
+// held of its own is free again. It runs in room.run().
+func (r *Room) retireClientID(conn *websocket.Conn, clientID string) {
+	r.mu.Lock()
+	r.forgetTypingLocked(clientID)
+	r.forgetKickVotesLocked(clientID)
+	delete(r.clientNames, clientID)
+	delete(r.revealed, clientID)
+	r.mu.Unlock()
+	slog.Info("Retired client ID of resumed connection", slog.String("room", r.id), slog.String("client", clientID), remoteAttr(conn))
+	r.logEvent("clientLeft", map[string]interface{}{
+		"clientId": clientID,
+	})
+	r.mode.OnClientLeave(r, conn)
+	r.runLeaveHook(clientID)
+	r.announcePresence("clientLeft", clientID)
+	r.dequeue()
+}
+
+// sendReconnected confirms a reconnect and brings the room up to date on the
+// current category.
+func (r *Room) sendReconnected(conn *websocket.Conn, token, clientID string) {
+	reconnectedMsg, _ := json.Marshal(map[string]interface{}{
+		"type":         "reconnected",
+		"sessionToken": token,
+		"clientId":     clientID,
+	})
+	if err := r.writeMessage(conn, websocket.TextMessage, reconnectedMsg); err != nil {
+		slog.Error("Error sending reconnected message", slog.String("room", r.id), remoteAttr(conn), slog.Any("error", err))
+	}
+
+	if len(r.usedCategories) > 0 {
+		categoryMsg, _ := json.Marshal(map[string]interface{}{
+			"type":  "newCategory",
+			"value": r.usedCategories[len(r.usedCategories)-1],
+		})
+		r.broadcastMessage(BroadcastMessage{
+			message:       categoryMsg,
+			sender:        conn,
+			msgType:       TypeNewCategory,
+			includeSender: true,
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReconnect(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxClients int
+		grace      time.Duration
+		// joinFirst makes the returning connection join on its own before
+		// it sends its session token
+		joinFirst bool
+	}{
+		{"reserved slot in full room", 2, 0, false},
+		{"reserved slot after joining", 3, 0, true},
+		{"graced slot after joining", 3, 5 * time.Second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, url := newTestServer(t, func(config *Config) {
+				config.DisconnectGrace = tt.grace
+			})
+			if _, _, err := s.getOrCreateRoom("resume", RoomOptions{MaxClients: tt.maxClients}); err != nil {
+				t.Fatalf("getOrCreateRoom: %v", err)
+			}
+
+			stays := dial(t, url, "room=resume")
+			expect(t, stays, "welcome")
+			leaves := dial(t, url, "room=resume")
+			welcome := expect(t, leaves, "welcome")
+			leaves.Close()
+			if tt.grace > 0 {
+				// Nothing is announced while the slot is graced; give
+				// room.run() time to notice the dropped connection
+				time.Sleep(100 * time.Millisecond)
+			} else if msg := expect(t, stays, "clientLeft"); msg["clientId"] != welcome["clientId"] {
+				t.Fatalf("clientLeft for %v, want %v", msg["clientId"], welcome["clientId"])
+			}
+
+			returning := dial(t, url, "room=resume")
+			var tempID interface{}
+			if tt.joinFirst {
+				tempID = expect(t, returning, "welcome")["clientId"]
+			}
+			send(t, returning, map[string]interface{}{"type": "reconnect", "token": welcome["sessionToken"]})
+			if msg := expect(t, returning, "reconnected"); msg["clientId"] != welcome["clientId"] {
+				t.Errorf("reconnected as %v, want %v", msg["clientId"], welcome["clientId"])
+			}
+
+			if tempID != nil {
+				// The client ID the returning connection joined with is gone
+				for {
+					if msg := expect(t, stays, "clientLeft"); msg["clientId"] == tempID {
+						break
+					}
+				}
+			}
+			send(t, stays, map[string]interface{}{"type": "roomInfo"})
+			if info := expect(t, stays, "roomInfo"); info["clientCount"] != float64(2) {
+				t.Errorf("clientCount = %v, want 2", info["clientCount"])
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// voteSkip records conn's vote to skip the current category. Once every
+// player voted, a new category is drawn; the skipped one stays used.
+func (r *Room) voteSkip(conn *websocket.Conn) {
+	r.mu.Lock()
+	clientID := r.clientIDs[conn]
+	if clientID == "" {
+		r.mu.Unlock()
+		return
+	}
+	if len(r.usedCategories) == 0 {
+		r.mu.Unlock()
+		if err := r.writeJSON(conn, map[string]interface{}{
+			"type": "error",
+			"code": "noCategory",
+		}); err != nil {
+			slog.Error("Error sending noCategory message", slog.String("room", r.id), slog.String("client", clientID), slog.Any("error", err))
+		}
+		return
+	}
+
+	r.skipVotes[clientID] = true
+	votes, required := len(r.skipVotes), len(r.clientIDs)
+	if votes < required {
+		r.mu.Unlock()
+		r.broadcastJSON(conn, map[string]interface{}{
+			"type":     "skipVoted",
+			"votes":    votes,
+			"required": required,
+		})
+		return
+	}
+
+	skipped := r.usedCategories[len(r.usedCategories)-1]
+	newCategory, reset, ok := getUniqueCategory(r.categories, r.usedCategories, nil)
+	if !ok {
+		r.mu.Unlock()
+		if err := r.writeJSON(conn, map[string]interface{}{
+			"type": "noMoreCategories",
+		}); err != nil {
+			slog.Error("Error sending noMoreCategories message", slog.String("room", r.id), slog.String("client", clientID), slog.Any("error", err))
+		}
+		return
+	}
+	r.server.recordCategoryUsage(newCategory)
+	r.skipVotes = make(map[string]bool)
+	r.logEventLocked("categorySkipped", map[string]interface{}{
+		"skipped":  skipped,
+		"category": newCategory,
+	})
+	r.mu.Unlock()
+
+	broadcastMsg, err := newBroadcast(conn, map[string]interface{}{
+		"type":        "categorySkipped",
+		"newCategory": newCategory,
+	})
+	if err != nil {
+		slog.Error("Error marshalling categorySkipped message", slog.String("room", r.id), slog.Any("error", err))
+		return
+	}
+	// room.run() records the new category when it delivers the message
+	broadcastMsg.category = newCategory
+	broadcastMsg.resetCategories = reset
+	broadcastMsg.timestamp = time.Now()
+	enqueue(r, r.broadcast, broadcastMsg)
+}
+
+// resetSkipVotes clears the skip votes when a new category is drawn.
+func (r *Room) resetSkipVotes() {
+	r.mu.Lock()
+	r.skipVotes = make(map[string]bool)
+	r.mu.Unlock()
+}
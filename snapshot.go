@@ -0,0 +1,133 @@
+package main
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// RoomSnapshot is the logical state of a room, without its connections.
+type RoomSnapshot struct {
+	RoomID         string         `json:"roomId"`
+	Clients        []string       `json:"clients"`
+	UsedCategories []string       `json:"usedCategories"`
+	Revealed       int            `json:"revealed"`
+	Round          int            `json:"round"`
+	Scores         map[string]int `json:"scores"`
+	LastActivity   time.Time      `json:"lastActivity"`
+	// CreatedAt is informational and ignored when a snapshot is restored
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// snapshotRequest asks room.run() for a snapshot, or to restore one if
+// restore is set. The room's state afterwards is sent on result.
+type snapshotRequest struct {
+	restore *RoomSnapshot
+	// sessions are reserved for their client IDs along with the restore
+	sessions map[string]string
+	result   chan RoomSnapshot
+}
+
+// Snapshot returns the current state of a room.
+func (s *Server) Snapshot(roomID string) (RoomSnapshot, error) {
+	return s.requestSnapshot(roomID, nil)
+}
+
+// RestoreSnapshot replaces the logical state of a room with a snapshot.
+// Connected clients stay connected.
+func (s *Server) RestoreSnapshot(roomID string, snapshot RoomSnapshot) (RoomSnapshot, error) {
+	return s.requestSnapshot(roomID, &snapshot)
+}
+
+func (s *Server) requestSnapshot(roomID string, restore *RoomSnapshot) (RoomSnapshot, error) {
+	room, ok := s.GetRoom(roomID)
+	if !ok {
+		return RoomSnapshot{}, ErrRoomNotFound
+	}
+
+	result := make(chan RoomSnapshot, 1)
+	select {
+	case room.snapshot <- snapshotRequest{restore: restore, result: result}:
+	case <-room.ctx.Done():
+		return RoomSnapshot{}, ErrRoomNotFound
+	}
+	return <-result, nil
+}
+
+// handleSnapshot runs in room.run(), so connections and game state cannot
+// change while the snapshot is taken or restored.
+func (r *Room) handleSnapshot(req snapshotRequest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if req.restore != nil {
+		r.restoreLocked(*req.restore)
+	}
+	for token, clientID := range req.sessions {
+		r.reserveSlotLocked(token, clientID)
+		r.reserved[token].restored = true
+	}
+	req.result <- r.snapshotLocked()
+}
+
+// snapshotLocked copies the room state. r.mu must be held.
+func (r *Room) snapshotLocked() RoomSnapshot {
+	clients := make([]string, 0, len(r.clientIDs))
+	for _, clientID := range r.clientIDs {
+		clients = append(clients, clientID)
+	}
+	sort.Strings(clients)
+
+	scores := make(map[string]int, len(r.scores))
+	for clientID, score := range r.scores {
+		scores[clientID] = score
+	}
+
+	return RoomSnapshot{
+		RoomID:         r.id,
+		Clients:        clients,
+		UsedCategories: append([]string(nil), r.usedCategories...),
+		Revealed:       len(r.revealed),
+		Round:          r.round,
+		Scores:         scores,
+		LastActivity:   r.lastActivity,
+		CreatedAt:      r.createdAt,
+	}
+}
+
+// restoreLocked applies a snapshot and restarts the current round. r.mu
+// must be held.
+func (r *Room) restoreLocked(snapshot RoomSnapshot) {
+	r.usedCategories = append([]string(nil), snapshot.UsedCategories...)
+	r.skipVotes = make(map[string]bool)
+	// Reveals are not tied to players in snapshots, so the restarted round
+	// starts without any
+	r.revealed = make(map[string]bool)
+	if snapshot.Round > 0 {
+		r.round = snapshot.Round
+	}
+	r.scores = make(map[string]int, len(snapshot.Scores))
+	for clientID, score := range snapshot.Scores {
+		r.scores[clientID] = score
+	}
+	r.roundScores = make(map[string]int)
+	if !snapshot.LastActivity.IsZero() {
+		r.lastActivity = snapshot.LastActivity
+	}
+
+	// The state follows from the restored round rather than a transition
+	switch {
+	case r.gameOverLocked():
+		r.state = StateEnded
+	case len(r.clients) == r.maxClients:
+		r.state = StatePlaying
+	default:
+		r.state = StateWaiting
+	}
+
+	r.stopRoundTimerLocked()
+	if len(r.clients) == r.maxClients {
+		r.startRoundTimerLocked()
+	}
+	slog.Info("Restored room snapshot", slog.String("room", r.id), slog.Int("round", r.round))
+}
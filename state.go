@@ -0,0 +1,86 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+)
+
+// RoomState is the phase of the game a room is in.
+type RoomState int
+
+const (
+	// StateWaiting is the state of a room that waits for players.
+	StateWaiting RoomState = iota
+	// StatePlaying is entered once the room filled up.
+	StatePlaying
+	// StateEnded is entered after the last round; "resetGame" leaves it.
+	StateEnded
+)
+
+func (s RoomState) String() string {
+	switch s {
+	case StateWaiting:
+		return "waiting"
+	case StatePlaying:
+		return "playing"
+	case StateEnded:
+		return "ended"
+	default:
+		return "unknown"
+	}
+}
+
+// roomTransitions lists the state changes a room may go through. A game
+// that loses a player waits for players again until the room is refilled.
+var roomTransitions = map[RoomState][]RoomState{
+	StateWaiting: {StatePlaying},
+	StatePlaying: {StateEnded, StateWaiting},
+	StateEnded:   {StateWaiting},
+}
+
+// stateMessageTypes maps message types that are only valid in one state to
+// that state.
+var stateMessageTypes = map[MessageType]RoomState{
+	TypeNewCategory:     StatePlaying,
+	TypeReveal:          StatePlaying,
+	TypeSkipCategory:    StatePlaying,
+	TypeNewRound:        StatePlaying,
+	TypeScore:           StatePlaying,
+	TypeGameEnd:         StatePlaying,
+	TypeCancelCountdown: StateWaiting,
+}
+
+// State returns the room's current state. It is safe to call from any
+// goroutine.
+func (r *Room) State() RoomState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state
+}
+
+// transitionLocked moves the room to state to and reports whether it did.
+// Transitions not listed in roomTransitions are refused. r.mu must be held.
+func (r *Room) transitionLocked(to RoomState) bool {
+	allowed := false
+	for _, next := range roomTransitions[r.state] {
+		allowed = allowed || next == to
+	}
+	if !allowed {
+		return false
+	}
+	slog.Info("Room state changed", slog.String("room", r.id), slog.String("from", r.state.String()), slog.String("to", to.String()))
+	r.state = to
+	return true
+}
+
+// sendInvalidState tells conn that its message is not valid in the room's
+// current state.
+func (r *Room) sendInvalidState(conn *websocket.Conn, current RoomState) {
+	if err := r.writeJSON(conn, map[string]interface{}{
+		"type":    "invalidState",
+		"current": current.String(),
+	}); err != nil {
+		slog.Error("Error sending invalidState message", slog.String("room", r.id), remoteAttr(conn), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestRoomStateTransitions(t *testing.T) {
+	states := []RoomState{StateWaiting, StatePlaying, StateEnded}
+	allowed := map[[2]RoomState]bool{
+		{StateWaiting, StatePlaying}: true,
+		{StatePlaying, StateEnded}:   true,
+		{StatePlaying, StateWaiting}: true,
+		{StateEnded, StateWaiting}:   true,
+	}
+	for _, from := range states {
+		for _, to := range states {
+			t.Run(from.String()+"->"+to.String(), func(t *testing.T) {
+				r := &Room{id: "test", state: from}
+				want := allowed[[2]RoomState{from, to}]
+				if got := r.transitionLocked(to); got != want {
+					t.Fatalf("transitionLocked(%s) from %s = %v, want %v", to, from, got, want)
+				}
+				wantState := from
+				if want {
+					wantState = to
+				}
+				if r.state != wantState {
+					t.Errorf("state = %s, want %s", r.state, wantState)
+				}
+			})
+		}
+	}
+}
+
+func TestRoomStateString(t *testing.T) {
+	tests := []struct {
+		state RoomState
+		want  string
+	}{
+		{StateWaiting, "waiting"},
+		{StatePlaying, "playing"},
+		{StateEnded, "ended"},
+		{RoomState(42), "unknown"},
+	}
+	for _, tt := range tests {
+		if got := tt.state.String(); got != tt.want {
+			t.Errorf("RoomState(%d).String() = %q, want %q", tt.state, got, tt.want)
+		}
+	}
+}
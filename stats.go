@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// statsTopCategories is how many of the most drawn categories Stats reports.
+const statsTopCategories = 10
+
+// ServerStats consolidates the server's operational metrics.
+type ServerStats struct {
+	Uptime                time.Duration    `json:"-"`
+	UptimeSeconds         float64          `json:"uptimeSeconds"`
+	TotalRoomsCreated     int64            `json:"totalRoomsCreated"`
+	TotalClientsConnected int64            `json:"totalClientsConnected"`
+	CurrentRooms          int              `json:"currentRooms"`
+	CurrentClients        int              `json:"currentClients"`
+	PeakRooms             int              `json:"peakRooms"`
+	PeakClients           int              `json:"peakClients"`
+	CategoryUsageTopN     []CategoryUsage  `json:"categoryUsageTopN"`
+	ErrorsByType          map[string]int64 `json:"errorsByType"`
+}
+
+// countError counts an error, both in the total and by its type.
+func (m *Metrics) countError(errorType string) {
+	m.errorCount.Add(1)
+	m.errorsTotal.WithLabelValues(errorType).Inc()
+	m.errorsMu.Lock()
+	m.errorsByType[errorType]++
+	m.errorsMu.Unlock()
+}
+
+// updatePeaks records new highs of open rooms and connected clients. It is
+// called after every increment of those gauges.
+func (m *Metrics) updatePeaks() {
+	raiseTo(&m.peakRooms, m.activeRooms.Load())
+	raiseTo(&m.peakClients, m.activePlayers.Load()+m.activeSpectators.Load())
+}
+
+// raiseTo sets peak to value if value is higher.
+func raiseTo(peak *atomic.Int64, value int64) {
+	for {
+		current := peak.Load()
+		if value <= current || peak.CompareAndSwap(current, value) {
+			return
+		}
+	}
+}
+
+// Stats returns a snapshot of the server's metrics.
+func (s *Server) Stats() ServerStats {
+	uptime := time.Since(s.startedAt)
+	stats := ServerStats{
+		Uptime:                uptime,
+		UptimeSeconds:         uptime.Seconds(),
+		TotalRoomsCreated:     s.metrics.roomsCreated.Load(),
+		TotalClientsConnected: s.metrics.clientsConnected.Load(),
+		CurrentRooms:          int(s.metrics.activeRooms.Load()),
+		CurrentClients:        int(s.metrics.activePlayers.Load() + s.metrics.activeSpectators.Load()),
+		PeakRooms:             int(s.metrics.peakRooms.Load()),
+		PeakClients:           int(s.metrics.peakClients.Load()),
+		ErrorsByType:          make(map[string]int64),
+	}
+
+	usage := s.CategoryUsage()
+	if len(usage) > statsTopCategories {
+		usage = usage[:statsTopCategories]
+	}
+	stats.CategoryUsageTopN = usage
+
+	s.metrics.errorsMu.Lock()
+	for errorType, count := range s.metrics.errorsByType {
+		stats.ErrorsByType[errorType] = count
+	}
+	s.metrics.errorsMu.Unlock()
+	return stats
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Stats())
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// mutexMetrics is the mutex-guarded layout Metrics used before its counters
+// became atomics. It is only kept to compare against in the benchmarks.
+type mutexMetrics struct {
+	mu            sync.Mutex
+	activeRooms   int64
+	activeClients int64
+	messagesTotal int64
+	errorCount    int64
+}
+
+// BenchmarkMetricsMutex and BenchmarkMetricsAtomic count a message from
+// every goroutine, the way reader goroutines do for each message they
+// process, while every hundredth iteration reads all counters like
+// handleMetrics. Run with -cpu 1,4,8 to see the contention.
+func BenchmarkMetricsMutex(b *testing.B) {
+	m := &mutexMetrics{}
+	b.RunParallel(func(pb *testing.PB) {
+		var n int
+		for pb.Next() {
+			m.mu.Lock()
+			m.messagesTotal++
+			m.mu.Unlock()
+			if n++; n%100 == 0 {
+				m.mu.Lock()
+				_ = m.activeRooms + m.activeClients + m.messagesTotal + m.errorCount
+				m.mu.Unlock()
+			}
+		}
+	})
+}
+
+func BenchmarkMetricsAtomic(b *testing.B) {
+	m := newMetrics(time.Now())
+	b.RunParallel(func(pb *testing.PB) {
+		var n int
+		for pb.Next() {
+			m.messagesTotal.Add(1)
+			if n++; n%100 == 0 {
+				_ = m.activeRooms.Load() + m.activePlayers.Load() + m.messagesTotal.Load() + m.errorCount.Load()
+			}
+		}
+	})
+}
+
+func TestRaiseTo(t *testing.T) {
+	tests := []struct {
+		name        string
+		peak, value int64
+		want        int64
+	}{
+		{"higher value raises", 3, 5, 5},
+		{"lower value keeps peak", 5, 3, 5},
+		{"equal value keeps peak", 4, 4, 4},
+		{"from zero", 0, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Metrics{}
+			m.peakRooms.Store(tt.peak)
+			raiseTo(&m.peakRooms, tt.value)
+			if got := m.peakRooms.Load(); got != tt.want {
+				t.Errorf("peak = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const storeSchema = `
+CREATE TABLE IF NOT EXISTS rooms (
+	id TEXT PRIMARY KEY,
+	pack TEXT NOT NULL,
+	max_clients INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	description TEXT NOT NULL,
+	round INTEGER NOT NULL,
+	revealed INTEGER NOT NULL,
+	used_categories TEXT NOT NULL,
+	sessions TEXT NOT NULL,
+	active INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+CREATE TABLE IF NOT EXISTS scores (
+	room_id TEXT NOT NULL,
+	client_id TEXT NOT NULL,
+	score INTEGER NOT NULL,
+	PRIMARY KEY (room_id, client_id)
+);
+CREATE TABLE IF NOT EXISTS room_events (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	room_id TEXT NOT NULL,
+	time TIMESTAMP NOT NULL,
+	type TEXT NOT NULL,
+	details TEXT NOT NULL
+);`
+
+// Store persists room state so rooms survive a server restart.
+type Store struct {
+	db *sql.DB
+}
+
+// storedRoom is the persisted state of a room.
+type storedRoom struct {
+	snapshot    RoomSnapshot
+	pack        string
+	maxClients  int
+	name        string
+	description string
+	// sessions maps session tokens to client IDs so players can reclaim
+	// their slot after a restart
+	sessions  map[string]string
+	createdAt time.Time
+}
+
+// OpenStore opens the SQLite database at path and creates missing tables.
+func OpenStore(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer at a time
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(storeSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// SaveRoom writes a room's state and the given events and marks it active.
+func (st *Store) SaveRoom(room storedRoom, events []RoomEvent) error {
+	usedCategories, err := json.Marshal(room.snapshot.UsedCategories)
+	if err != nil {
+		return err
+	}
+	sessions, err := json.Marshal(room.sessions)
+	if err != nil {
+		return err
+	}
+
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO rooms (id, pack, max_clients, name, description, round, revealed, used_categories, sessions, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET pack = excluded.pack, max_clients = excluded.max_clients,
+			name = excluded.name, description = excluded.description, round = excluded.round,
+			revealed = excluded.revealed, used_categories = excluded.used_categories,
+			sessions = excluded.sessions, active = 1, updated_at = excluded.updated_at`,
+		room.snapshot.RoomID, room.pack, room.maxClients, room.name, room.description,
+		room.snapshot.Round, room.snapshot.Revealed, string(usedCategories), string(sessions),
+		room.createdAt, room.snapshot.LastActivity)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM scores WHERE room_id = ?`, room.snapshot.RoomID); err != nil {
+		return err
+	}
+	for clientID, score := range room.snapshot.Scores {
+		if _, err := tx.Exec(`INSERT INTO scores (room_id, client_id, score) VALUES (?, ?, ?)`, room.snapshot.RoomID, clientID, score); err != nil {
+			return err
+		}
+	}
+
+	for _, event := range events {
+		details, err := json.Marshal(event.Details)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO room_events (room_id, time, type, details) VALUES (?, ?, ?, ?)`, room.snapshot.RoomID, event.Time, event.Type, string(details)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeactivateRoom marks a room as closed so it is not restored.
+func (st *Store) DeactivateRoom(id string) error {
+	_, err := st.db.Exec(`UPDATE rooms SET active = 0 WHERE id = ?`, id)
+	return err
+}
+
+// ActiveRooms returns the active rooms updated after since.
+func (st *Store) ActiveRooms(since time.Time) ([]storedRoom, error) {
+	rows, err := st.db.Query(`SELECT id, pack, max_clients, name, description, round, revealed, used_categories, sessions, created_at, updated_at
+		FROM rooms WHERE active = 1 AND updated_at > ?`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rooms []storedRoom
+	for rows.Next() {
+		var room storedRoom
+		var usedCategories, sessions string
+		err := rows.Scan(&room.snapshot.RoomID, &room.pack, &room.maxClients, &room.name, &room.description,
+			&room.snapshot.Round, &room.snapshot.Revealed, &usedCategories, &sessions, &room.createdAt, &room.snapshot.LastActivity)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(usedCategories), &room.snapshot.UsedCategories); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(sessions), &room.sessions); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, room)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range rooms {
+		scores, err := st.scores(rooms[i].snapshot.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		rooms[i].snapshot.Scores = scores
+	}
+	return rooms, nil
+}
+
+func (st *Store) scores(roomID string) (map[string]int, error) {
+	rows, err := st.db.Query(`SELECT client_id, score FROM scores WHERE room_id = ?`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scores := make(map[string]int)
+	for rows.Next() {
+		var clientID string
+		var score int
+		if err := rows.Scan(&clientID, &score); err != nil {
+			return nil, err
+		}
+		scores[clientID] = score
+	}
+	return scores, rows.Err()
+}
+
+// Close closes the database.
+func (st *Store) Close() error {
+	return st.db.Close()
+}
+
+// persistState saves the room state and the events logged since the last
+// save. It runs in room.run(), which owns the session maps.
+func (r *Room) persistState() {
+	store := r.server.store
+	if store == nil {
+		return
+	}
+
+	sessions := make(map[string]string, len(r.sessions)+len(r.reserved))
+	for token, reservation := range r.reserved {
+		sessions[token] = reservation.clientID
+	}
+
+	r.mu.Lock()
+	for conn, token := range r.sessions {
+		sessions[token] = r.clientIDs[conn]
+	}
+	snapshot := r.snapshotLocked()
+	var events []RoomEvent
+	for i := range r.eventLog {
+		event := r.eventLog[(r.eventStart+i)%len(r.eventLog)]
+		if event.Time.After(r.persistedAt) {
+			events = append(events, event)
+		}
+	}
+	r.persistedAt = time.Now()
+	name, description := r.name, r.description
+	r.mu.Unlock()
+
+	err := store.SaveRoom(storedRoom{
+		snapshot:    snapshot,
+		pack:        r.pack,
+		maxClients:  r.maxClients,
+		name:        name,
+		description: description,
+		sessions:    sessions,
+		createdAt:   r.createdAt,
+	}, events)
+	if err != nil {
+		slog.Error("Error persisting room", slog.String("room", r.id), slog.Any("error", err))
+	}
+}
+
+// restoreRooms recreates the rooms that were active within RoomTimeout
+// before the server stopped. Their players can reclaim their slots with
+// their session tokens.
+func (s *Server) restoreRooms() {
+	rooms, err := s.store.ActiveRooms(time.Now().Add(-s.config.RoomTimeout))
+	if err != nil {
+		slog.Error("Error loading persisted rooms", slog.Any("error", err))
+		return
+	}
+
+	for _, stored := range rooms {
+		room, _, err := s.getOrCreateRoom(stored.snapshot.RoomID, RoomOptions{
+			MaxClients:   stored.maxClients,
+			CategoryPack: stored.pack,
+			Name:         stored.name,
+			Description:  stored.description,
+		})
+		if err != nil {
+			slog.Error("Error restoring room", slog.String("room", stored.snapshot.RoomID), slog.Any("error", err))
+			continue
+		}
+
+		snapshot := stored.snapshot
+		result := make(chan RoomSnapshot, 1)
+		select {
+		case room.snapshot <- snapshotRequest{restore: &snapshot, sessions: stored.sessions, result: result}:
+			<-result
+		case <-room.ctx.Done():
+			continue
+		}
+		slog.Info("Restored room", slog.String("room", room.id), slog.Int("round", snapshot.Round), slog.Int("sessions", len(stored.sessions)))
+	}
+}
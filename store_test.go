@@ -0,0 +1,64 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	st, err := OpenStore(filepath.Join(t.TempDir(), "rooms.db"))
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	defer st.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	saved := storedRoom{
+		snapshot: RoomSnapshot{
+			RoomID:         "abc",
+			UsedCategories: []string{"Tiere", "Farben"},
+			Revealed:       1,
+			Round:          3,
+			Scores:         map[string]int{"c1": 2, "c2": 5},
+			LastActivity:   now,
+		},
+		pack:        "default",
+		maxClients:  2,
+		name:        "Test",
+		description: "A room",
+		sessions:    map[string]string{"token": "c1"},
+		createdAt:   now.Add(-time.Hour),
+	}
+	events := []RoomEvent{{Time: now, Type: "roundEnd", Details: map[string]interface{}{"round": 3}}}
+	if err := st.SaveRoom(saved, events); err != nil {
+		t.Fatalf("SaveRoom: %v", err)
+	}
+
+	rooms, err := st.ActiveRooms(now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("ActiveRooms: %v", err)
+	}
+	if len(rooms) != 1 {
+		t.Fatalf("ActiveRooms returned %d rooms, want 1", len(rooms))
+	}
+	got := rooms[0]
+	if !got.snapshot.LastActivity.Equal(now) || !got.createdAt.Equal(saved.createdAt) {
+		t.Errorf("times = %v, %v, want %v, %v", got.snapshot.LastActivity, got.createdAt, now, saved.createdAt)
+	}
+	got.snapshot.LastActivity, got.createdAt = saved.snapshot.LastActivity, saved.createdAt
+	if !reflect.DeepEqual(got, saved) {
+		t.Errorf("ActiveRooms = %+v, want %+v", got, saved)
+	}
+
+	if rooms, err := st.ActiveRooms(now); err != nil || len(rooms) != 0 {
+		t.Errorf("ActiveRooms after the last update = %d rooms, %v, want none", len(rooms), err)
+	}
+	if err := st.DeactivateRoom("abc"); err != nil {
+		t.Fatalf("DeactivateRoom: %v", err)
+	}
+	if rooms, err := st.ActiveRooms(now.Add(-time.Minute)); err != nil || len(rooms) != 0 {
+		t.Errorf("ActiveRooms after DeactivateRoom = %d rooms, %v, want none", len(rooms), err)
+	}
+}
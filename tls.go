@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net"
+	"net/http"
+)
+
+// tlsEnabled reports whether both a certificate and a key are configured. In
+// that mode the static client, the API and the /ws endpoint (as wss://) are
+// all served over HTTPS.
+func (c Config) tlsEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// newRedirectServer returns a plain HTTP server that redirects every request
+// to the HTTPS listener on Config.Port.
+func newRedirectServer(config Config) *http.Server {
+	return &http.Server{
+		Addr:         "0.0.0.0:" + config.RedirectPort,
+		ReadTimeout:  config.ReadTimeout,
+		WriteTimeout: config.WriteTimeout,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, _, err := net.SplitHostPort(r.Host)
+			if err != nil {
+				host = r.Host
+			}
+			if config.Port != "443" {
+				host = net.JoinHostPort(host, config.Port)
+			}
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+		}),
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// typingDebounce is the minimum time between two forwarded typing
+	// indicators of a client that keeps typing.
+	typingDebounce = 2 * time.Second
+	// typingTimeout is how long a client counts as typing after its last
+	// indicator.
+	typingTimeout = 5 * time.Second
+)
+
+// typingState is what the room last told the peers about a client typing.
+type typingState struct {
+	isTyping  bool
+	forwarded time.Time
+	timeout   *time.Timer
+}
+
+// setTyping forwards a client's typing indicator to its peers as
+// "peerTyping". Changes are forwarded right away, repeated indicators at
+// most every typingDebounce. A client that sends no indicator for
+// typingTimeout is reported as no longer typing. It runs in the
+// connection's reader goroutine.
+func (r *Room) setTyping(conn *websocket.Conn, msg map[string]interface{}) {
+	isTyping, _ := msg["isTyping"].(bool)
+	now := time.Now()
+
+	r.mu.Lock()
+	clientID := r.clientIDs[conn]
+	if clientID == "" {
+		r.mu.Unlock()
+		return
+	}
+	state := r.typing[clientID]
+	if state == nil {
+		state = &typingState{}
+		r.typing[clientID] = state
+	}
+	if state.timeout != nil {
+		state.timeout.Stop()
+		state.timeout = nil
+	}
+	if isTyping {
+		state.timeout = time.AfterFunc(typingTimeout, func() {
+			r.expireTyping(clientID)
+		})
+	}
+	forward := isTyping != state.isTyping || (isTyping && now.Sub(state.forwarded) >= typingDebounce)
+	if forward {
+		state.isTyping = isTyping
+		state.forwarded = now
+	}
+	r.mu.Unlock()
+
+	if forward {
+		r.sendPeerTyping(conn, clientID, isTyping)
+	}
+}
+
+// expireTyping reports a client as no longer typing once typingTimeout
+// passed without a new indicator. The client is looked up by its ID, as it
+// may have reconnected in the meantime. It runs in the indicator's timer
+// goroutine.
+func (r *Room) expireTyping(clientID string) {
+	r.mu.Lock()
+	state := r.typing[clientID]
+	if state == nil || !state.isTyping || r.ctx.Err() != nil {
+		r.mu.Unlock()
+		return
+	}
+	state.isTyping = false
+	state.forwarded = time.Now()
+	state.timeout = nil
+	var conn *websocket.Conn
+	for client, id := range r.clientIDs {
+		if id == clientID {
+			conn = client
+			break
+		}
+	}
+	r.mu.Unlock()
+
+	r.sendPeerTyping(conn, clientID, false)
+}
+
+// forgetTypingLocked drops the typing state of a client that left. r.mu
+// must be held.
+func (r *Room) forgetTypingLocked(clientID string) {
+	if state := r.typing[clientID]; state != nil && state.timeout != nil {
+		state.timeout.Stop()
+	}
+	delete(r.typing, clientID)
+}
+
+// sendPeerTyping queues a "peerTyping" for everyone but the typing client.
+func (r *Room) sendPeerTyping(conn *websocket.Conn, clientID string, isTyping bool) {
+	broadcastMsg, err := newBroadcast(conn, map[string]interface{}{
+		"type":     "peerTyping",
+		"clientId": clientID,
+		"isTyping": isTyping,
+	})
+	if err != nil {
+		slog.Error("Error marshalling peerTyping message", slog.String("room", r.id), slog.String("client", clientID), slog.Any("error", err))
+		return
+	}
+	broadcastMsg.includeSender = false
+	broadcastMsg.timestamp = time.Now()
+	enqueue(r, r.broadcast, broadcastMsg)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// CategoryUsage is how often a category has been drawn since the server
+// started.
+type CategoryUsage struct {
+	Category string `json:"category"`
+	Count    int64  `json:"count"`
+}
+
+// recordCategoryUsage counts a drawn category unless
+// Config.TrackCategoryUsage is off.
+func (s *Server) recordCategoryUsage(category string) {
+	if !s.config.TrackCategoryUsage {
+		return
+	}
+	s.usageMu.Lock()
+	s.categoryUsage[category]++
+	s.usageMu.Unlock()
+}
+
+// CategoryUsage returns the drawn categories, most used first.
+func (s *Server) CategoryUsage() []CategoryUsage {
+	s.usageMu.RLock()
+	usage := make([]CategoryUsage, 0, len(s.categoryUsage))
+	for category, count := range s.categoryUsage {
+		usage = append(usage, CategoryUsage{Category: category, Count: count})
+	}
+	s.usageMu.RUnlock()
+
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Count != usage[j].Count {
+			return usage[i].Count > usage[j].Count
+		}
+		return usage[i].Category < usage[j].Category
+	})
+	return usage
+}
+
+func (s *Server) handleCategoryMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.CategoryUsage())
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	webhookRetries = 3
+	webhookBackoff = time.Second
+)
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// webhookEvent is the payload posted to Config.WebhookURL.
+type webhookEvent struct {
+	Event     string    `json:"event"`
+	RoomID    string    `json:"roomId"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// notifyWebhook posts a room lifecycle event to Config.WebhookURL in the
+// background. It does nothing if no URL is configured.
+func (s *Server) notifyWebhook(event, roomID string) {
+	if s.config.WebhookURL == "" {
+		return
+	}
+
+	body, err := json.Marshal(webhookEvent{
+		Event:     event,
+		RoomID:    roomID,
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		slog.Error("Error marshalling webhook event", slog.String("event", event), slog.Any("error", err))
+		return
+	}
+	go s.deliverWebhook(event, body)
+}
+
+// deliverWebhook sends the body, retrying with exponential backoff.
+func (s *Server) deliverWebhook(event string, body []byte) {
+	mac := hmac.New(sha256.New, []byte(s.config.WebhookSecret))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	backoff := webhookBackoff
+	var err error
+	for attempt := 0; attempt <= webhookRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		if err = postWebhook(s.config.WebhookURL, signature, body); err == nil {
+			return
+		}
+		slog.Debug("Webhook delivery failed", slog.String("event", event), slog.Int("attempt", attempt+1), slog.Any("error", err))
+	}
+	slog.Error("Giving up on webhook delivery", slog.String("event", event), slog.String("url", s.config.WebhookURL), slog.Any("error", err))
+}
+
+func postWebhook(url, signature string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Spiele-Signature", signature)
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
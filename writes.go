@@ -0,0 +1,65 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// gorilla/websocket supports a single concurrent writer per connection, but
+// both room.run() and the connection's reader goroutine write to it. Every
+// write therefore goes through writeMessage, which serializes writes with a
+// per-connection mutex.
+
+// addWriter sets up the write mutex for a connection. It is called by the
+// connection's reader goroutine before anything else can write to it.
+func (r *Room) addWriter(conn *websocket.Conn) {
+	r.connsMu.Lock()
+	r.writeMu[conn] = &sync.Mutex{}
+	r.connsMu.Unlock()
+}
+
+// removeWriter forgets the write mutex once the reader goroutine returns.
+func (r *Room) removeWriter(conn *websocket.Conn) {
+	r.connsMu.Lock()
+	delete(r.writeMu, conn)
+	r.connsMu.Unlock()
+}
+
+// writeMessage writes a single frame to conn. Connections without a reader
+// goroutine, e.g. while authenticating, have just one writer and are written
+// to directly.
+func (r *Room) writeMessage(conn *websocket.Conn, frameType int, data []byte) error {
+	r.connsMu.Lock()
+	mu := r.writeMu[conn]
+	r.connsMu.Unlock()
+	if mu != nil {
+		mu.Lock()
+		defer mu.Unlock()
+	}
+	return conn.WriteMessage(frameType, data)
+}
+
+// SendToClient writes a text message to a single connection, e.g. a private
+// message from a game mode. It must only be called from room.run(), such as
+// from GameMode.OnClientJoin.
+func (r *Room) SendToClient(conn *websocket.Conn, msg []byte) error {
+	return r.writeMessage(conn, websocket.TextMessage, msg)
+}
+
+// SendToAll writes a text message to every player and spectator. Players
+// whose connection fails are dropped. Unlike a broadcast, the message is not
+// tagged, batched, kept in the history or published to other instances. It
+// must only be called from room.run().
+func (r *Room) SendToAll(msg []byte) {
+	for client := range r.clients {
+		if client == nil || r.inGrace(client) {
+			continue
+		}
+		if err := r.SendToClient(client, msg); err != nil {
+			r.dropClient(client, err)
+		}
+	}
+	r.writeSpectators(websocket.TextMessage, msg)
+	r.announceDropped()
+}